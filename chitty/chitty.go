@@ -0,0 +1,425 @@
+// Package chitty lets Go programs take part in a Chitty-Chat as participants,
+// e.g. bots. A Client joins the server, publishes and leaves through
+// CCService, and serves its own ParticipantService to receive the
+// broadcasts, which it hands to the handlers registered with OnMessage,
+// OnJoin and OnLeave:
+//
+//	bot := chitty.New(chitty.Options{Server: "localhost:5454", ID: 42, Nickname: "echo"})
+//	bot.OnMessage(func(m chitty.Message) {
+//		if m.ClientID != bot.ID() && !m.Direct && !strings.HasPrefix(m.Text, "echo: ") {
+//			bot.Publish(context.Background(), "echo: "+m.Text)
+//		}
+//	})
+//	if err := bot.Join(context.Background()); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer bot.Leave(context.Background())
+//
+// The Client keeps the participant's Lamport clock. Calls wait for the
+// connection to the server to come back, and a participant the server no
+// longer knows, e.g. after a restart, joins again by itself.
+package chitty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/dedup"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Options configure a Client.
+type Options struct {
+	Server    string        // host:port of the server
+	ID        int64         // client id, unique in the chat
+	Nickname  string        // shown next to the messages; none when empty
+	Listen    string        // address the ParticipantService listens at, ":0" when empty
+	Advertise string        // host:port the server calls back at; localhost and the listening port when empty
+	Timeout   time.Duration // of every call to the server, 10s when zero
+	Rejoin    time.Duration // how often to check that the server still knows the participant, 5s when zero
+
+	// Credentials the server is dialed with, e.g. credentials.NewTLS for a
+	// server with TLS; insecure when nil
+	Credentials credentials.TransportCredentials
+
+	Listener    net.Listener      // serve the ParticipantService on this listener instead of Listen, e.g. in tests
+	DialOptions []grpc.DialOption // added to the options the server is dialed with
+}
+
+// Message is a message broadcast by the server.
+type Message struct {
+	ClientID     int64 // the publisher, -1 for an announcement
+	Nickname     string
+	Room         string
+	Text         string
+	Lamport      int64 // the participant's Lamport time after receiving it
+	Direct       bool  // sent to this participant only, with /dm
+	Announcement bool  // sent by an operator
+}
+
+// Join is the broadcast that a participant joined, including this one.
+type Join struct {
+	ClientID int64
+	Lamport  int64
+}
+
+// Leave is the broadcast that a participant left.
+type Leave struct {
+	ClientID int64
+	Reason   string // left or kicked
+	Lamport  int64
+}
+
+// ErrInvalidMessage is returned by Publish for a message the server would not accept.
+var ErrInvalidMessage = errors.New("not a valid message: send 1 to 128 characters of UTF-8")
+
+// ErrInvalidNickname is returned by Join for a nickname the server would not accept.
+var ErrInvalidNickname = errors.New("not a valid nickname: use 1 to 32 UTF-8 characters")
+
+// ErrNotJoined is returned by calls that need the participant to be in the chat.
+var ErrNotJoined = errors.New("not in the chat")
+
+// Client is a participant. Its methods may be called from the handlers.
+type Client struct {
+	proto.UnimplementedParticipantServiceServer
+	opts Options
+
+	mu         sync.Mutex // guards the fields below, held while joining
+	conn       *grpc.ClientConn
+	server     proto.CCServiceClient
+	grpcServer *grpc.Server
+	address    string
+	port       int
+	joined     bool
+	done       chan struct{} // closed when the participant left or was kicked
+
+	handlersMu sync.Mutex // guards the handlers, which are read while the server broadcasts our join
+	onMessage  func(Message)
+	onJoin     func(Join)
+	onLeave    func(Leave)
+
+	events    chan func()   // handler calls, run in order by dispatch
+	delivered *dedup.Window // ids of the messages received recently, to drop copies
+
+	clockMu     sync.Mutex // guards lamportTime
+	lamportTime int64
+}
+
+// New returns a client that has not joined yet.
+func New(opts Options) *Client {
+	if opts.Listen == "" {
+		opts.Listen = ":0"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Rejoin == 0 {
+		opts.Rejoin = 5 * time.Second
+	}
+	return &Client{
+		opts:        opts,
+		done:        make(chan struct{}),
+		events:      make(chan func(), 256),
+		delivered:   dedup.NewWindow(1024),
+		lamportTime: 1,
+	}
+}
+
+// ID returns the client id of the participant.
+func (c *Client) ID() int64 {
+	return c.opts.ID
+}
+
+// Lamport returns the current Lamport time of the participant.
+func (c *Client) Lamport() int64 {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	return c.lamportTime
+}
+
+// Done is closed when the participant has left the chat or was kicked out.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// OnMessage sets the handler of published, direct and announced messages.
+// Handlers run one at a time in the order the broadcasts arrived.
+func (c *Client) OnMessage(handle func(Message)) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.onMessage = handle
+}
+
+// OnJoin sets the handler of join broadcasts.
+func (c *Client) OnJoin(handle func(Join)) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.onJoin = handle
+}
+
+// OnLeave sets the handler of leave broadcasts. A Leave with the participant's
+// own id means it was kicked out, which closes Done as well.
+func (c *Client) OnLeave(handle func(Leave)) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.onLeave = handle
+}
+
+// Join starts the ParticipantService, connects to the server and joins the chat.
+func (c *Client) Join(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.grpcServer != nil {
+		return errors.New("already joined")
+	}
+	if nickname := strings.TrimSpace(c.opts.Nickname); c.opts.Nickname != "" && (nickname == "" || !utf8.ValidString(nickname) || utf8.RuneCountInString(nickname) > 32) {
+		return ErrInvalidNickname
+	}
+
+	listener := c.opts.Listener
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen("tcp", c.opts.Listen); err != nil {
+			return err
+		}
+	}
+	if address, ok := listener.Addr().(*net.TCPAddr); ok {
+		c.port = address.Port
+	}
+	c.address = c.opts.Advertise
+	if c.address == "" {
+		c.address = net.JoinHostPort("localhost", strconv.Itoa(c.port))
+	}
+
+	// calls wait for the connection to the server instead of failing while it is down
+	creds := c.opts.Credentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	options := append([]grpc.DialOption{grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.WaitForReady(true))}, c.opts.DialOptions...)
+	conn, err := grpc.Dial(c.opts.Server, options...)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	c.conn = conn
+	c.server = proto.NewCCServiceClient(conn)
+
+	c.grpcServer = grpc.NewServer()
+	proto.RegisterParticipantServiceServer(c.grpcServer, c)
+	go c.grpcServer.Serve(listener)
+
+	if err := c.join(ctx); err != nil {
+		c.grpcServer.Stop()
+		conn.Close()
+		c.grpcServer = nil
+		return err
+	}
+	c.joined = true
+	go c.dispatch()
+	go c.watch()
+	return nil
+}
+
+// join sends the join request and the nickname; c.mu must be held
+func (c *Client) join(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	reply, err := c.server.ParticipantJoins(ctx, &proto.ClientInfo{
+		ClientId:    c.opts.ID,
+		LamportTime: c.tick(),
+		PortNumber:  int64(c.port),
+		Address:     c.address,
+	})
+	if err != nil {
+		return fmt.Errorf("join: %w", err)
+	}
+	c.receive(reply.LamportTime)
+
+	if c.opts.Nickname == "" {
+		return nil
+	}
+	reply, err = c.server.SetNickname(ctx, &proto.ClientInfo{
+		ClientId:    c.opts.ID,
+		LamportTime: c.tick(),
+		Nickname:    c.opts.Nickname,
+	})
+	if err != nil {
+		// do not stay in the chat without the nickname; ctx may be what ran out
+		leaveCtx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+		defer cancel()
+		if _, leaveErr := c.server.ParticipantLeaves(leaveCtx, &proto.ClientInfo{
+			ClientId:    c.opts.ID,
+			LamportTime: c.tick(),
+		}); leaveErr != nil {
+			slog.Warn("could not leave after a failed nickname", "client", c.opts.ID, "error", leaveErr)
+		}
+		return fmt.Errorf("nickname: %w", err)
+	}
+	c.receive(reply.LamportTime)
+	return nil
+}
+
+// Publish tries publishAttempts times, waiting retryBackoff longer before every retry
+const (
+	publishAttempts = 3
+	retryBackoff    = 100 * time.Millisecond
+)
+
+// Publish sends text to the server, which broadcasts it to the participants in
+// the room. A call that times out or does not reach the server is tried again,
+// up to three times in all, with the same message id, so that the message is
+// broadcast once.
+func (c *Client) Publish(ctx context.Context, text string) error {
+	if text == "" || !utf8.ValidString(text) || utf8.RuneCountInString(text) > 128 {
+		return ErrInvalidMessage
+	}
+	server, err := c.connected()
+	if err != nil {
+		return err
+	}
+
+	id := dedup.NewID()
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+		reply, err := server.ParticipantMessages(callCtx, &proto.ClientInfo{
+			ClientId:    c.opts.ID,
+			LamportTime: c.tick(),
+			Message:     text,
+			MessageId:   id,
+		})
+		cancel()
+		if err == nil {
+			c.receive(reply.LamportTime)
+			return nil
+		}
+		if code := status.Code(err); attempt == publishAttempts || ctx.Err() != nil || (code != codes.Unavailable && code != codes.DeadlineExceeded) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+	}
+}
+
+// Leave takes the participant out of the chat and stops its ParticipantService.
+func (c *Client) Leave(ctx context.Context) error {
+	server, err := c.connected()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	_, err = server.ParticipantLeaves(ctx, &proto.ClientInfo{
+		ClientId:    c.opts.ID,
+		LamportTime: c.tick(),
+	})
+	c.stop()
+	return err
+}
+
+// connected returns the server while the participant is in the chat
+func (c *Client) connected() (proto.CCServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.joined {
+		return nil, ErrNotJoined
+	}
+	return c.server, nil
+}
+
+// stop closes Done, the ParticipantService and the connection, once
+func (c *Client) stop() {
+	c.mu.Lock()
+	if !c.joined {
+		c.mu.Unlock()
+		return
+	}
+	c.joined = false
+	close(c.done)
+	c.mu.Unlock()
+
+	c.grpcServer.Stop()
+	c.conn.Close()
+}
+
+// watch joins again when the server no longer lists the participant
+func (c *Client) watch() {
+	ticker := time.NewTicker(c.opts.Rejoin)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+		list, err := c.server.Participants(ctx, &proto.ClientInfo{ClientId: c.opts.ID, LamportTime: c.tick()})
+		cancel()
+		if err != nil {
+			continue // the connection is down, the next check tries again
+		}
+		c.receive(list.LamportTime)
+		if listed(list.Participants, c.opts.ID) {
+			continue
+		}
+
+		c.mu.Lock()
+		if c.joined {
+			slog.Info("server no longer lists the participant, joining again", "client", c.opts.ID)
+			if err := c.join(context.Background()); err != nil {
+				slog.Warn("could not join again", "client", c.opts.ID, "error", err)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func listed(participants []*proto.ClientInfo, id int64) bool {
+	for _, participant := range participants {
+		if participant.ClientId == id {
+			return true
+		}
+	}
+	return false
+}
+
+// tick advances the Lamport clock for a send and returns the new time
+func (c *Client) tick() int64 {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	c.lamportTime++
+	return c.lamportTime
+}
+
+// receive merges a received timestamp into the Lamport clock and returns the new time
+func (c *Client) receive(lamportTime int64) int64 {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	if c.lamportTime < lamportTime {
+		c.lamportTime = lamportTime
+	}
+	c.lamportTime++
+	return c.lamportTime
+}
@@ -0,0 +1,175 @@
+package chitty_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Tien197/Chitty-Chat/chitty"
+	"github.com/Tien197/Chitty-Chat/internal/chattest"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"strings"
+	"testing"
+	"time"
+)
+
+const botID = 42
+
+// join starts a bot on the harness's network after configure, unless nil, has
+// set its handlers, and makes it leave when the test ends
+func join(t *testing.T, h *chattest.Harness, opts chitty.Options, configure func(*chitty.Client)) *chitty.Client {
+	t.Helper()
+
+	name := chattest.Host(int(opts.ID))
+	opts.Server = chattest.ServerAddress
+	opts.Advertise = name
+	opts.Listener = h.Transport.Listen(name)
+	opts.DialOptions = h.Transport.DialOptions(name)
+	opts.Timeout = 2 * time.Second
+
+	bot := chitty.New(opts)
+	if configure != nil {
+		configure(bot)
+	}
+	if err := bot.Join(context.Background()); err != nil {
+		t.Fatalf("the bot could not join: %v", err)
+	}
+	t.Cleanup(func() { bot.Leave(context.Background()) })
+	return bot
+}
+
+// listed reports whether the server lists participant id, with its nickname
+func listed(t *testing.T, h *chattest.Harness, id int64) (string, bool) {
+	t.Helper()
+
+	list, err := h.Server.Participants(context.Background(), &proto.ClientInfo{ClientId: id})
+	if err != nil {
+		t.Fatalf("could not list the participants: %v", err)
+	}
+	for _, participant := range list.Participants {
+		if participant.ClientId == id {
+			return participant.Nickname, true
+		}
+	}
+	return "", false
+}
+
+// eventually waits up to two seconds for condition
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestJoinSetsTheNickname(t *testing.T) {
+	h := chattest.New(t)
+	joins := make(chan chitty.Join, 10)
+	join(t, h, chitty.Options{ID: botID, Nickname: "echo"}, func(bot *chitty.Client) {
+		bot.OnJoin(func(j chitty.Join) { joins <- j })
+	})
+
+	if nickname, ok := listed(t, h, botID); !ok || nickname != "echo" {
+		t.Fatalf("the server lists the bot as %q (in the chat: %v), want echo", nickname, ok)
+	}
+
+	h.Join(1)
+	for {
+		select {
+		case j := <-joins:
+			if j.ClientID == 1 {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("the bot's join handler did not see participant 1 join")
+		}
+	}
+}
+
+func TestPublishReachesTheParticipants(t *testing.T) {
+	h := chattest.New(t)
+	messages := make(chan chitty.Message, 10)
+	bot := join(t, h, chitty.Options{ID: botID}, func(bot *chitty.Client) {
+		bot.OnMessage(func(m chitty.Message) { messages <- m })
+	})
+	c := h.Join(1)
+
+	if err := c.Publish("hello bot"); err != nil {
+		t.Fatalf("participant 1 could not publish: %v", err)
+	}
+	select {
+	case m := <-messages:
+		if m.ClientID != 1 || m.Text != "hello bot" || m.Room != "lobby" || m.Direct || m.Announcement {
+			t.Errorf("the bot's handler got %+v, want the message of participant 1", m)
+		}
+		if m.Lamport <= 1 {
+			t.Errorf("the bot's Lamport time %d did not advance", m.Lamport)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the bot's message handler was not called")
+	}
+
+	if err := bot.Publish(context.Background(), "hello participant"); err != nil {
+		t.Fatalf("the bot could not publish: %v", err)
+	}
+	received := h.Received(chattest.Host(1), "broadcast")
+	if len(received) != 2 || !strings.Contains(received[1].Text, "hello participant") {
+		t.Errorf("participant 1 received %v, want the bot's message", received)
+	}
+}
+
+func TestPublishRejectsInvalidMessages(t *testing.T) {
+	h := chattest.New(t)
+	bot := join(t, h, chitty.Options{ID: botID}, nil)
+
+	for _, text := range []string{"", "\xff", strings.Repeat("x", 129)} {
+		if err := bot.Publish(context.Background(), text); !errors.Is(err, chitty.ErrInvalidMessage) {
+			t.Errorf("publishing %q returned %v, want ErrInvalidMessage", text, err)
+		}
+	}
+}
+
+// TestKickClosesDone kicks the bot while its handlers lag so far behind that
+// the broadcasts are dropped.
+func TestKickClosesDone(t *testing.T) {
+	h := chattest.New(t)
+	release := make(chan struct{})
+	defer close(release)
+	bot := join(t, h, chitty.Options{ID: botID}, func(bot *chitty.Client) {
+		bot.OnMessage(func(chitty.Message) { <-release })
+	})
+
+	for i := 0; i < 300; i++ {
+		if _, err := bot.ClientMessageReturn(context.Background(), &proto.ClientInfo{ClientId: 1, Message: "flood"}); err != nil {
+			t.Fatalf("could not flood the bot: %v", err)
+		}
+	}
+	if _, err := bot.ClientLeaveReturn(context.Background(), &proto.ClientInfo{ClientId: botID, Message: "kicked"}); err != nil {
+		t.Fatalf("could not kick the bot: %v", err)
+	}
+
+	select {
+	case <-bot.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done was not closed after the kick")
+	}
+	if err := bot.Publish(context.Background(), "still here"); !errors.Is(err, chitty.ErrNotJoined) {
+		t.Errorf("publishing after the kick returned %v, want ErrNotJoined", err)
+	}
+}
+
+func TestRejoinWhenTheServerForgets(t *testing.T) {
+	h := chattest.New(t)
+	join(t, h, chitty.Options{ID: botID, Nickname: "echo", Rejoin: 50 * time.Millisecond}, nil)
+
+	// as after a restart, the server no longer knows the bot
+	if _, err := h.Server.ParticipantLeaves(context.Background(), &proto.ClientInfo{ClientId: botID}); err != nil {
+		t.Fatalf("could not remove the bot: %v", err)
+	}
+	if !eventually(func() bool {
+		nickname, ok := listed(t, h, botID)
+		return ok && nickname == "echo"
+	}) {
+		t.Error("the bot did not join again with its nickname")
+	}
+}
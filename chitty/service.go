@@ -0,0 +1,86 @@
+package chitty
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"log/slog"
+)
+
+// The ParticipantService handlers only merge the timestamp and queue the
+// handler call, so a slow handler does not hold back the server's broadcast.
+// dispatch runs the queued calls in order.
+
+// when the server broadcasts that a participant joined
+func (c *Client) ClientJoinReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := c.receive(in.LamportTime)
+	c.handlersMu.Lock()
+	handle := c.onJoin
+	c.handlersMu.Unlock()
+	if handle != nil {
+		c.queue(func() { handle(Join{ClientID: in.ClientId, Lamport: lamportTime}) })
+	}
+	return &proto.ServerInfo{LamportTime: lamportTime}, nil
+}
+
+// when the server broadcasts a published, direct or announced message
+func (c *Client) ClientMessageReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := c.receive(in.LamportTime)
+	c.handlersMu.Lock()
+	handle := c.onMessage
+	c.handlersMu.Unlock()
+	if in.MessageId != "" && c.delivered.Seen(in.MessageId) {
+		return &proto.ServerInfo{LamportTime: lamportTime}, nil // a copy of a message handled before
+	}
+	if handle != nil {
+		c.queue(func() {
+			handle(Message{
+				ClientID:     in.ClientId,
+				Nickname:     in.Nickname,
+				Room:         in.Room,
+				Text:         in.Message,
+				Lamport:      lamportTime,
+				Direct:       in.RecipientId != 0,
+				Announcement: in.Announcement,
+			})
+		})
+	}
+	return &proto.ServerInfo{LamportTime: lamportTime}, nil
+}
+
+// when the server broadcasts that a participant left or was kicked
+func (c *Client) ClientLeaveReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := c.receive(in.LamportTime)
+	c.handlersMu.Lock()
+	handle := c.onLeave
+	c.handlersMu.Unlock()
+	if handle != nil {
+		c.queue(func() { handle(Leave{ClientID: in.ClientId, Reason: in.Message, Lamport: lamportTime}) })
+	}
+	if in.ClientId == c.opts.ID {
+		// kicked out: not queued, which may drop the call, and apart from this
+		// call, since stop ends the ParticipantService serving it
+		go c.stop()
+	}
+	return &proto.ServerInfo{LamportTime: lamportTime}, nil
+}
+
+// queue hands a handler call to dispatch, dropping it when the handlers lag far behind
+func (c *Client) queue(call func()) {
+	select {
+	case c.events <- call:
+	default:
+		slog.Warn("handlers lag behind, dropping a broadcast", "client", c.opts.ID)
+	}
+}
+
+// dispatch runs the handler calls until the participant left
+func (c *Client) dispatch() {
+	for {
+		select {
+		case call := <-c.events:
+			call()
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -1,147 +1,583 @@
-package main
+// Package client implements a Chitty-Chat participant: it joins the server,
+// publishes what is typed, scripted or entered in the terminal UI, and serves
+// the ParticipantService the server broadcasts to, as well as the floor
+// control and election calls between participants. cmd/chitty-client runs it
+// from the command line; other programs and tests start one with New and Start.
+package client
 
 import (
 	"bufio"
 	"context"
-	"flag"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/dedup"
+	"github.com/Tien197/Chitty-Chat/eventlog"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/metrics"
 	"github.com/Tien197/Chitty-Chat/proto"
+	"github.com/Tien197/Chitty-Chat/tracing"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
-	"log"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
 	"net"
-	"os"
-	"os/signal"
 	"strconv"
-	"syscall"
+	"strings"
+	"sync"
+	"time"
 	"unicode/utf8"
 )
 
+// Options configure a Client: the settings of the config file, and what a
+// program embedding the participant can hand it instead of a port, e.g. an
+// in-memory listener and dialer in tests.
+type Options struct {
+	config.Client
+	Listener    net.Listener      // serve the ParticipantService on this listener instead of Port
+	DialOptions []grpc.DialOption // added to the options the server and the peers are dialed with
+}
+
+// Client is a participant.
 type Client struct {
 	proto.UnimplementedParticipantServiceServer // Necessary
 	id                                          int
 	portNumber                                  int
+	address                                     string // host:port advertised to the server and the peers
+	opts                                        Options
 	lamportTime                                 int
+	clockMu                                     sync.Mutex // guards lamportTime
+	conn                                        *grpc.ClientConn
+	server                                      proto.CCServiceClient
+	grpcServer                                  *grpc.Server // nil until started
+	floor                                       *floor       // nil unless running in mutex mode
+	election                                    *election    // nil unless electing a moderator
+	peers                                       map[int64]*grpc.ClientConn
+	peersMu                                     sync.Mutex
+	delivered                                   *dedup.Window // ids of the messages received recently
+	events                                      *eventlog.Logger
+	logger                                      *slog.Logger
+	joined                                      bool
+	kicked                                      chan struct{} // closed when an operator removes us from the chat
+	kickOnce                                    sync.Once     // the kick broadcast may arrive more than once
+	stopped                                     chan struct{} // closed by Stop
+	stopOnce                                    sync.Once
+	tui                                         *tea.Program    // nil unless running the terminal UI
+	metrics                                     *metrics.Server // nil unless serving metrics
+	clockGauge                                  prometheus.Collector
 }
 
-var (
-	clientPort = flag.Int("cPort", 0, "client port number")
-	serverPort = flag.Int("sPort", 0, "server port number (should match the port used for the server)")
-	clientID   = flag.Int("id", 0, "client ID number")
-)
+// deliveredWindow is the number of message ids a participant remembers to drop copies
+const deliveredWindow = 1024
 
-func main() {
-	// Parse the flags to get the port for the client
-	flag.Parse()
+// New returns a participant that has not joined yet. The event log is opened here.
+func New(opts Options) (*Client, error) {
+	if opts.Timeouts.Connect == 0 {
+		opts.Timeouts.Connect = 5 * time.Second
+	}
+	if opts.Timeouts.RPC == 0 {
+		opts.Timeouts.RPC = 10 * time.Second
+	}
+
+	events, err := eventlog.Open(participantHost(int64(opts.ID)), opts.EventLog)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the event log: %w", err)
+	}
 
-	// Create a client
 	client := &Client{
-		id:          *clientID,
-		portNumber:  *clientPort,
+		id:          opts.ID,
+		portNumber:  opts.Port,
+		address:     opts.Advertise,
+		opts:        opts,
 		lamportTime: 1,
+		peers:       make(map[int64]*grpc.ClientConn),
+		delivered:   dedup.NewWindow(deliveredWindow),
+		events:      events,
+		logger:      slog.With(logging.Client, opts.ID),
+		kicked:      make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	if opts.Mutex {
+		client.floor = newFloor()
 	}
-	// Starts the client
-	go startClient(client)
+	if opts.Election != "" {
+		client.election = newElection(opts.Election)
+	}
+	return client, nil
+}
 
-	// Wait for the client (user) to ask for the time
-	go waitForJoinRequest(client)
+// Start serves the ParticipantService, connects to the server and joins the chat.
+func (client *Client) Start() error {
+	listener := client.opts.Listener
+	if listener == nil {
+		var err error
+		listener, err = net.Listen("tcp", ":"+strconv.Itoa(client.portNumber))
+		if err != nil {
+			return fmt.Errorf("could not create the server: %w", err)
+		}
+	}
+	if address, ok := listener.Addr().(*net.TCPAddr); ok {
+		client.portNumber = address.Port // the port picked by the system for port 0
+	}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT)
+	// Register the grpc server and serve its listener
+	client.grpcServer = grpc.NewServer(grpc.UnaryInterceptor(metrics.UnaryServerInterceptor()), tracing.ServerOption())
+	proto.RegisterParticipantServiceServer(client.grpcServer, client)
+	go func() {
+		if err := client.grpcServer.Serve(listener); err != nil {
+			client.logger.Error("could not serve listener", "error", err)
+		}
+	}()
 
-	// Block until a signal is received
-	<-sigChan
+	if client.opts.Metrics != "" {
+		metricsServer, err := metrics.Serve(client.opts.Metrics)
+		if err != nil {
+			client.grpcServer.Stop()
+			return err
+		}
+		client.metrics = metricsServer
+		client.clockGauge = registerClock(client)
+	}
 
-	client.lamportTime++
-	log.Printf("Client %d disconnected from the server at Lamport time %d", client.id, client.lamportTime)
-}
+	if err := client.connectToServer(); err != nil {
+		client.grpcServer.Stop()
+		client.stopMetrics()
+		return err
+	}
+	if err := client.join(); err != nil {
+		client.grpcServer.Stop()
+		client.stopMetrics()
+		client.conn.Close()
+		return err
+	}
+	if err := client.settle(); err != nil {
+		client.leave()
+		client.grpcServer.Stop()
+		client.stopMetrics()
+		client.conn.Close()
+		return err
+	}
+	client.joined = true
 
-func startClient(client *Client) {
+	if client.election != nil {
+		go client.watchModerator()
+	}
+	return nil
+}
 
-	// Create a new grpc server
-	grpcServer := grpc.NewServer()
+// Stop leaves the chat, unless the participant was kicked out, stops the
+// ParticipantService and the metrics, and closes the event log.
+func (client *Client) Stop() error {
+	var err error
+	client.stopOnce.Do(func() { err = client.stop() })
+	return err
+}
 
-	// Make the server listen at the given port (convert int port to string)
-	listener, err := net.Listen("tcp", ":"+strconv.Itoa(client.portNumber))
+func (client *Client) stop() error {
+	var err error
+	if client.joined {
+		err = client.leave()
+	}
+	close(client.stopped)
+	if client.grpcServer != nil {
+		client.grpcServer.Stop()
+	}
+	metricsErr := client.stopMetrics()
 
-	if err != nil {
-		log.Fatalf("Could not create the server %v", err)
+	client.peersMu.Lock()
+	for id, conn := range client.peers {
+		conn.Close()
+		delete(client.peers, id)
 	}
-	// Register the grpc server and serve its listener
-	proto.RegisterParticipantServiceServer(grpcServer, client)
+	client.peersMu.Unlock()
+	if client.conn != nil {
+		client.conn.Close()
+	}
+
+	lamportTime := client.local("disconnect", fmt.Sprintf("Client %d disconnected", client.id))
+	client.logger.Info("client disconnected", logging.Event, "disconnect", logging.Lamport, lamportTime)
+	return errors.Join(err, metricsErr, client.events.Close())
+}
 
-	serveError := grpcServer.Serve(listener)
-	if serveError != nil {
-		log.Fatalf("Could not serve listener")
+// stopMetrics stops serving the metrics, if they are served
+func (client *Client) stopMetrics() error {
+	if client.metrics == nil {
+		return nil
 	}
+	if client.clockGauge != nil {
+		prometheus.Unregister(client.clockGauge)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+	return client.metrics.Shutdown(ctx)
 }
 
-func waitForJoinRequest(client *Client) {
-	// Connect to the server
-	serverConnection, _ := connectToServer(client)
+// Kicked is closed when an operator removes the participant from the chat.
+func (client *Client) Kicked() <-chan struct{} {
+	return client.kicked
+}
 
-	client.lamportTime++
-	log.Printf("Client %d requests to join server at Lamport Time %d", client.id, client.lamportTime)
+// ReadInput publishes the lines read from in and runs the ones starting with /
+// as commands, writing their output to out. It reports whether the participant
+// should leave (/leave) rather than in having ended.
+func (client *Client) ReadInput(in io.Reader, out io.Writer) bool {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		input := scanner.Text()
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		if !strings.HasPrefix(input, "/") {
+			client.Publish(input)
+			continue
+		}
 
-	_, err := serverConnection.ParticipantJoins(context.Background(), &proto.ClientInfo{
+		output, err := client.RunCommand(input)
+		switch {
+		case errors.Is(err, errLeave):
+			return true
+		case err != nil:
+			fmt.Fprintln(out, status.Convert(err).Message())
+		case output != "":
+			fmt.Fprintln(out, output)
+		}
+	}
+	return false
+}
+
+// join asks the server to add this participant to the chat
+func (client *Client) join() error {
+	lamportTime, vectorClock := client.send("join", serverHost, fmt.Sprintf("Client %d requests to join server", client.id))
+	client.logger.Info("client requests to join", logging.Event, "join", logging.Lamport, lamportTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.Connect)
+	defer cancel()
+	_, err := client.server.ParticipantJoins(ctx, &proto.ClientInfo{
 		ClientId:    int64(client.id),
-		LamportTime: int64(client.lamportTime),
+		LamportTime: int64(lamportTime),
 		PortNumber:  int64(client.portNumber),
-	})
+		Address:     client.address,
+		VectorClock: vectorClock,
+	}, grpc.WaitForReady(true)) // the server may still be starting, e.g. in the compose demo
 
 	if err != nil {
-		log.Printf("Client %d could not join server", client.id)
+		client.logger.Error("client could not join", logging.Event, "join", "error", err)
 	}
-	if err != nil {
-		log.Printf("Error in ParticipantJoins: %v", err)
+	return err
+}
+
+// settle sets the configured nickname and moves to the configured room
+func (client *Client) settle() error {
+	if client.opts.Nickname != "" {
+		if _, err := client.nickCommand(client.opts.Nickname); err != nil {
+			client.logger.Error("client could not set its nickname", logging.Event, "nick", "error", err)
+			return fmt.Errorf("could not set the nickname: %w", err)
+		}
 	}
+	if client.opts.Room != "" {
+		if _, err := client.joinCommand(client.opts.Room); err != nil {
+			client.logger.Error("client could not join its room", logging.Event, "room", "error", err)
+			return fmt.Errorf("could not join room %s: %w", client.opts.Room, err)
+		}
+	}
+	return nil
+}
 
-	// Wait for input in the client terminal
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		input := scanner.Text()
+// validMessage reports whether input may be published: UTF-8 and at most 128 characters
+func validMessage(input string) bool {
+	return utf8.ValidString(input) && utf8.RuneCountInString(input) <= 128
+}
+
+// Publish tries publishAttempts times, waiting retryBackoff longer before every retry
+const (
+	publishAttempts = 3
+	retryBackoff    = 100 * time.Millisecond
+)
+
+// errInvalidMessage is returned for messages that fail validMessage
+var errInvalidMessage = errors.New("not a valid message: send UTF-8 of at most 128 characters")
 
-		if utf8.ValidString(input) && len(input) <= 128 {
-			log.Printf("Client %d publishes message: \"%s\" at Lamport Time ...\n", client.id, input)
-		} else {
-			log.Print("Not a valid message! Send a message of UTF-8 and within 128 characters in length.")
+// Publish sends a message to the server, which broadcasts it to every participant in the room
+func (client *Client) Publish(input string) error {
+	if !validMessage(input) {
+		client.logger.Warn("Not a valid message! Send a message of UTF-8 and within 128 characters in length.", logging.Event, "publish")
+		return errInvalidMessage
+	}
+
+	// Only the participant holding the floor may publish
+	if client.floor != nil {
+		if err := client.acquireFloor(); err != nil {
+			failedPublishes.Inc()
+			client.logger.Error("client could not get the floor", logging.Event, "floor-request", "message", input, "error", err)
+			return err
 		}
+	}
 
-		// Ask the server for the time
-		clientReturnMessage, err := serverConnection.ParticipantMessages(context.Background(), &proto.ClientInfo{
-			ClientId: int64(client.id),
-			Message:  input,
-		})
+	messageID := dedup.NewID()
+	lamportTime, vectorClock := client.send("publish", serverHost, fmt.Sprintf("Client %d publishes message %q", client.id, input))
+	client.logger.Info("client publishes message", logging.Event, "publish", logging.MessageID, messageID, logging.Lamport, lamportTime, "message", input)
 
-		if err != nil {
-			log.Printf(err.Error())
-		} else {
-			log.Printf("%s broadcasts client {id}'s \"%s\" at Lamport Time ...\n", clientReturnMessage.ServerName, input)
+	// the span covers the broadcast to every participant, which the server does before replying
+	ctx, span := tracing.Tracer().Start(context.Background(), "publish", trace.WithAttributes(
+		attribute.Int("chitty.publisher", client.id),
+		attribute.Int("chitty.lamport", lamportTime),
+		attribute.String("chitty.message_id", messageID),
+	))
+	defer span.End()
+
+	// A publish that timed out or did not reach the server is sent again with the
+	// same id; the server drops the copy if an earlier attempt got through. One
+	// rejected while another participant holds the floor is tried again too.
+	var clientReturnMessage *proto.ServerInfo
+	var err error
+	for attempt := 1; ; attempt++ {
+		rpcCtx, cancel := context.WithTimeout(ctx, client.opts.Timeouts.RPC)
+		clientReturnMessage, err = client.server.ParticipantMessages(rpcCtx, &proto.ClientInfo{
+			ClientId:    int64(client.id),
+			LamportTime: int64(lamportTime),
+			Message:     input,
+			MessageId:   messageID,
+			VectorClock: vectorClock,
+		})
+		cancel()
+		if code := status.Code(err); attempt == publishAttempts || (code != grpccodes.Unavailable && code != grpccodes.DeadlineExceeded && code != grpccodes.FailedPrecondition) {
+			break
 		}
+
+		time.Sleep(time.Duration(attempt) * retryBackoff)
+		lamportTime, vectorClock = client.send("publish", serverHost, fmt.Sprintf("Client %d publishes message %q again", client.id, input))
+		client.logger.Warn("client retries publish", logging.Event, "publish", logging.MessageID, messageID, "attempt", attempt+1, logging.Lamport, lamportTime, "error", err)
 	}
+
+	if client.floor != nil {
+		client.releaseFloor()
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
+		failedPublishes.Inc()
+		client.logger.Error("client could not publish message", logging.Event, "publish", "message", input, "error", err)
+		return err
+	}
+	publishes.Inc()
+	client.logger.Info("server accepted message", logging.Event, "publish-reply", "server", clientReturnMessage.ServerName, logging.Lamport, clientReturnMessage.LamportTime, "message", input,
+		"delivered", clientReturnMessage.Delivered, "recipients", clientReturnMessage.Recipients)
+	client.notify(deliveredMsg{delivered: clientReturnMessage.Delivered, recipients: clientReturnMessage.Recipients})
+	return nil
 }
 
-func connectToServer(client *Client) (proto.CCServiceClient, error) {
-	// Dial the server at the specified port.
-	conn, err := grpc.Dial("localhost:"+strconv.Itoa(*serverPort), grpc.WithTransportCredentials(insecure.NewCredentials()))
+// DirectMessage sends a message that only the participant recipient receives
+func (client *Client) DirectMessage(recipient int64, input string) error {
+	if !validMessage(input) {
+		return errInvalidMessage
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("dm", serverHost, fmt.Sprintf("Client %d sends a direct message to Participant %d", client.id, recipient))
+	client.logger.Info("client sends direct message", logging.Event, "dm", logging.Participant, recipient, logging.Lamport, lamportTime)
+	_, err := client.server.DirectMessage(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		Message:     input,
+		RecipientId: recipient,
+		VectorClock: vectorClock,
+	})
 	if err != nil {
-		log.Fatalf("Could not connect to port %d", *serverPort)
-	} else {
-		log.Printf("Client %d connected to the server at port %d at Lamport Time %d\n", client.id, *serverPort, client.lamportTime)
+		client.logger.Error("client could not send direct message", logging.Event, "dm", logging.Participant, recipient, "error", err)
 	}
-	return proto.NewCCServiceClient(conn), nil
+	return err
+}
+
+// leave tells the server that this participant leaves the chat
+func (client *Client) leave() error {
+	select {
+	case <-client.kicked:
+		return nil // the server has already removed us
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("leave", serverHost, fmt.Sprintf("Client %d leaves server", client.id))
+	client.logger.Info("client leaves", logging.Event, "leave", logging.Lamport, lamportTime)
+
+	_, err := client.server.ParticipantLeaves(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		client.logger.Error("client could not leave", logging.Event, "leave", "error", err)
+	}
+	return err
+}
+
+// connectToServer dials the server at the configured address
+func (client *Client) connectToServer() error {
+	address := client.opts.ServerAddress()
+	creds, err := client.opts.ServerCredentials()
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", address, err)
+	}
+	options := append([]grpc.DialOption{grpc.WithTransportCredentials(creds), tracing.DialOption()}, client.opts.DialOptions...)
+	conn, err := grpc.Dial(address, options...)
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", address, err)
+	}
+	client.logger.Info("client connected to server", logging.Event, "connect", "address", address, logging.Lamport, client.clock())
+	client.conn = conn
+	client.server = proto.NewCCServiceClient(conn)
+	return nil
+}
+
+// dialOptions are used for the peers, which serve without TLS
+func (client *Client) dialOptions() []grpc.DialOption {
+	return append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials()), tracing.DialOption()}, client.opts.DialOptions...)
 }
 
 // could be refactored
 func (client *Client) ClientJoinReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := client.received("join-broadcast", serverHost, in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d joined", in.ClientId))
 
-	if client.lamportTime < int(in.LamportTime) {
-		client.lamportTime = int(in.LamportTime)
+	client.logger.Info("participant joined", logging.Event, "join-broadcast", logging.Participant, in.ClientId, logging.Lamport, lamportTime)
+	broadcastsReceived.WithLabelValues("join").Inc()
+	client.notify(joinedMsg{id: in.ClientId, lamport: lamportTime})
+
+	return &proto.ServerInfo{
+		LamportTime: int64(lamportTime),
+	}, nil
+}
+
+// when the server broadcasts a published message
+func (client *Client) ClientMessageReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	_, span := tracing.Tracer().Start(ctx, "receive message", trace.WithAttributes(
+		attribute.Int64("chitty.publisher", in.ClientId),
+		attribute.Int("chitty.recipient", client.id),
+	))
+	defer span.End()
+
+	// a copy of a message delivered before, e.g. of a retried publish, is not shown again
+	if in.MessageId != "" && client.delivered.Seen(in.MessageId) {
+		lamportTime := client.received("broadcast", serverHost, in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d dropped a copy of message %q from Participant %d", client.id, in.Message, in.ClientId))
+		span.SetAttributes(attribute.Int("chitty.lamport", lamportTime), attribute.Bool("chitty.duplicate", true))
+		client.logger.Debug("dropped duplicate message", logging.Event, "broadcast", logging.Participant, in.ClientId, logging.MessageID, in.MessageId, logging.Lamport, lamportTime)
+		duplicateDeliveries.Inc()
+		return &proto.ServerInfo{
+			LamportTime: int64(lamportTime),
+		}, nil
 	}
-	client.lamportTime++
 
-	log.Printf("Client %d joined at lamport timestamp %d\n", in.ClientId, client.lamportTime)
+	lamportTime := client.received("broadcast", serverHost, in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d received message %q from Participant %d", client.id, in.Message, in.ClientId))
+	span.SetAttributes(attribute.Int("chitty.lamport", lamportTime))
+
+	switch {
+	case in.Announcement:
+		client.logger.Info("server announcement", logging.Event, "announcement", logging.Lamport, lamportTime, "message", in.Message)
+	case in.RecipientId != 0:
+		client.logger.Info("direct message", logging.Event, "dm", logging.Participant, in.ClientId, logging.Lamport, lamportTime, "message", in.Message)
+	default:
+		client.logger.Info("participant message", logging.Event, "broadcast", logging.Participant, in.ClientId, "nickname", in.Nickname, logging.Lamport, lamportTime, "message", in.Message)
+	}
+	broadcastsReceived.WithLabelValues("message").Inc()
+	client.notify(chatMsg{from: in.ClientId, nickname: in.Nickname, lamport: lamportTime, text: in.Message, announcement: in.Announcement, direct: in.RecipientId != 0})
+	if client.wantsReceipt(in) {
+		go client.sendReadReceipt(in) // the server is still broadcasting, do not hold it up
+	}
 
 	return &proto.ServerInfo{
-		LamportTime: int64(client.lamportTime),
+		LamportTime: int64(lamportTime),
 	}, nil
 }
+
+// when the server broadcasts that a participant left or was kicked
+func (client *Client) ClientLeaveReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := client.received("leave-broadcast", serverHost, in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d %s", in.ClientId, in.Message))
+
+	client.logger.Info("participant left", logging.Event, "leave-broadcast", logging.Participant, in.ClientId, "reason", in.Message, logging.Lamport, lamportTime)
+	broadcastsReceived.WithLabelValues("leave").Inc()
+	client.notify(leftMsg{id: in.ClientId, lamport: lamportTime, reason: in.Message})
+
+	if in.ClientId == int64(client.id) {
+		client.kickOnce.Do(func() {
+			client.logger.Warn("client was kicked from the chat", logging.Event, "kicked", logging.Lamport, lamportTime)
+			close(client.kicked)
+		})
+	}
+
+	return &proto.ServerInfo{
+		LamportTime: int64(lamportTime),
+	}, nil
+}
+
+// serverHost is the name of the server in the event log
+const serverHost = "server"
+
+// participantHost is the name of a participant in the event log
+func participantHost(clientID int64) string {
+	return fmt.Sprintf("client-%d", clientID)
+}
+
+// send advances the Lamport clock for sending kind to peer and logs the send in
+// the same step, so that concurrent calls log the participant's events in
+// Lamport order. It returns the new time and the vector clock to attach.
+func (client *Client) send(kind, peer, text string) (int, map[string]int64) {
+	client.clockMu.Lock()
+	defer client.clockMu.Unlock()
+
+	client.lamportTime++
+	vectorClock := client.events.Send(kind, peer, client.lamportTime, text)
+	if client.opts.Clock == config.LamportOnly {
+		vectorClock = nil
+	}
+	return client.lamportTime, vectorClock
+}
+
+// received merges the timestamps of a message of kind from peer into the clocks
+// and logs the receive in the same step. It returns the new Lamport time.
+func (client *Client) received(kind, peer string, lamportTime int64, vectorClock map[string]int64, text string) int {
+	client.clockMu.Lock()
+	defer client.clockMu.Unlock()
+
+	if client.lamportTime < int(lamportTime) {
+		client.lamportTime = int(lamportTime)
+	}
+	client.lamportTime++
+	client.events.Receive(kind, peer, client.lamportTime, vectorClock, text)
+	return client.lamportTime
+}
+
+// local advances the Lamport clock for a local event, logs it and returns the new time
+func (client *Client) local(kind, text string) int {
+	client.clockMu.Lock()
+	defer client.clockMu.Unlock()
+
+	client.lamportTime++
+	client.events.LocalEvent(kind, client.lamportTime, text)
+	return client.lamportTime
+}
+
+// clock returns the current Lamport time
+func (client *Client) clock() int {
+	client.clockMu.Lock()
+	defer client.clockMu.Unlock()
+
+	return client.lamportTime
+}
+
+// participantAddress returns the host:port of the participant's ParticipantService
+func participantAddress(participant *proto.ClientInfo) string {
+	if participant.Address != "" {
+		return participant.Address
+	}
+	return "localhost:" + strconv.Itoa(int(participant.PortNumber))
+}
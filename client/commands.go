@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lines starting with / are commands rather than chat messages, in the terminal
+// and in the TUI alike. A command only has to be registered to be available.
+
+// command is run with the text after its name, and returns what to show the user
+type command struct {
+	usage string // the arguments, for /help
+	help  string
+	run   func(client *Client, args string) (string, error)
+}
+
+var commands = map[string]command{}
+
+// registerCommand makes /name available in the input line
+func registerCommand(name string, cmd command) {
+	commands[name] = cmd
+}
+
+// errLeave is returned by /leave; the input loop ends and the participant leaves
+var errLeave = errors.New("leaving the chat")
+
+func init() {
+	registerCommand("nick", command{"<name>", "choose the name shown next to your messages", (*Client).nickCommand})
+	registerCommand("who", command{"", "list the participants", (*Client).whoCommand})
+	registerCommand("join", command{"<room>", "move to another room, messages only reach your room", (*Client).joinCommand})
+	registerCommand("leave", command{"", "leave the chat", func(*Client, string) (string, error) { return "", errLeave }})
+	registerCommand("history", command{"[N]", "show the last N messages of your room (10)", (*Client).historyCommand})
+	registerCommand("clock", command{"", "show your clocks and the server's Lamport time", (*Client).clockCommand})
+	registerCommand("dm", command{"<id> <message>", "send a message only participant <id> receives", (*Client).dmCommand})
+	registerCommand("help", command{"", "list the commands", helpCommand})
+}
+
+// RunCommand runs line, which starts with /, and returns what to show the user.
+func (client *Client) RunCommand(line string) (string, error) {
+	name, args, _ := strings.Cut(strings.TrimPrefix(line, "/"), " ")
+	cmd, ok := commands[name]
+	if !ok {
+		return "", fmt.Errorf("unknown command /%s, try /help", name)
+	}
+	client.logger.Debug("client runs command", logging.Event, "command", "command", name)
+	return cmd.run(client, strings.TrimSpace(args))
+}
+
+func helpCommand(*Client, string) (string, error) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%-22s %s", strings.TrimSpace("/"+name+" "+commands[name].usage), commands[name].help))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (client *Client) nickCommand(args string) (string, error) {
+	if args == "" {
+		return "", errors.New("usage: /nick <name>")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("nick", serverHost, fmt.Sprintf("Client %d is now called %q", client.id, args))
+	_, err := client.server.SetNickname(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		Nickname:    args,
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		return "", err
+	}
+	return "you are now " + args, nil
+}
+
+func (client *Client) whoCommand(string) (string, error) {
+	participants, err := client.participants()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(participants))
+	for _, participant := range participants {
+		names = append(names, fmt.Sprintf("%s in %s", displayName(participant.ClientId, participant.Nickname), participant.Room))
+	}
+	return fmt.Sprintf("%d in the chat: %s", len(names), strings.Join(names, ", ")), nil
+}
+
+func (client *Client) joinCommand(args string) (string, error) {
+	if args == "" {
+		return "", errors.New("usage: /join <room>")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("room", serverHost, fmt.Sprintf("Client %d moves to room %q", client.id, args))
+	_, err := client.server.JoinRoom(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		Room:        args,
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		return "", err
+	}
+	return "you are now in " + args, nil
+}
+
+func (client *Client) historyCommand(args string) (string, error) {
+	count := 10
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 {
+			return "", errors.New("usage: /history [N], N is a positive number")
+		}
+		count = n
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("history", serverHost, fmt.Sprintf("Client %d asks for %d messages", client.id, count))
+	history, err := client.server.History(ctx, &proto.HistoryRequest{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		Count:       int64(count),
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		return "", err
+	}
+	lamportTime = client.received("history-reply", serverHost, history.LamportTime, history.VectorClock, fmt.Sprintf("Client %d got %d messages", client.id, len(history.Messages)))
+
+	if len(history.Messages) == 0 {
+		return "no messages yet", nil
+	}
+	lines := make([]string, 0, len(history.Messages))
+	for _, message := range history.Messages {
+		lines = append(lines, fmt.Sprintf("[L%d] %s: %s", message.LamportTime, displayName(message.ClientId, message.Nickname), message.Message))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (client *Client) clockCommand(string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("clock", serverHost, fmt.Sprintf("Client %d asks for the clock", client.id))
+	reply, err := client.server.Clock(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		return "", err
+	}
+	lamportTime = client.received("clock-reply", serverHost, reply.LamportTime, reply.VectorClock, fmt.Sprintf("Client %d got the server's clock", client.id))
+
+	vector, _ := json.Marshal(client.events.Clock())
+	return fmt.Sprintf("Lamport time %d (server %d), vector clock %s", lamportTime, reply.LamportTime, vector), nil
+}
+
+func (client *Client) dmCommand(args string) (string, error) {
+	to, text, _ := strings.Cut(args, " ")
+	recipient, err := strconv.ParseInt(to, 10, 64)
+	if err != nil || strings.TrimSpace(text) == "" {
+		return "", errors.New("usage: /dm <id> <message>")
+	}
+	if err := client.DirectMessage(recipient, text); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("you → %s: %s", participantHost(recipient), text), nil
+}
+
+// displayName shows the nickname of a participant next to its id
+func displayName(clientID int64, nickname string) string {
+	if nickname == "" {
+		return participantHost(clientID)
+	}
+	return fmt.Sprintf("%s (%s)", nickname, participantHost(clientID))
+}
@@ -0,0 +1,375 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Leader election of a moderator among the participants. The bully variant lets
+// the participant with the highest id win; the ring variant passes a candidate
+// list around the participants ordered by id. Either way the winner is
+// announced to everyone with a Coordinator call, and an election is started
+// again when the moderator stops answering heartbeats. Participants started
+// without -election reject the election calls with FailedPrecondition, so that
+// the others skip them as if they were unreachable.
+
+const (
+	heartbeatInterval = 2 * time.Second
+	electionTimeout   = 3 * time.Second
+)
+
+type election struct {
+	mu            sync.Mutex
+	mode          string // "bully" or "ring"
+	running       bool
+	moderator     *proto.ClientInfo // nil while no moderator is known
+	announcements int               // number of Coordinator messages seen, used to detect a stalled election
+}
+
+func newElection(mode string) *election {
+	return &election{mode: mode}
+}
+
+// watchModerator starts an election when there is no moderator or the current one
+// does not answer a heartbeat
+func (client *Client) watchModerator() {
+	for {
+		client.election.mu.Lock()
+		moderator := client.election.moderator
+		client.election.mu.Unlock()
+
+		switch {
+		case moderator == nil:
+			client.startElection()
+		case moderator.ClientId != int64(client.id):
+			var reply *proto.ClientInfo
+			moderatorClient, err := client.participant(moderator)
+			if err == nil {
+				lamportTime, vectorClock := client.send("heartbeat", participantHost(moderator.ClientId), fmt.Sprintf("Client %d checks that moderator %d is alive", client.id, moderator.ClientId))
+				ctx, cancel := context.WithTimeout(context.Background(), heartbeatInterval)
+				reply, err = moderatorClient.Heartbeat(ctx, &proto.ClientInfo{
+					ClientId:    int64(client.id),
+					LamportTime: int64(lamportTime),
+					VectorClock: vectorClock,
+				})
+				cancel()
+			}
+			if err != nil {
+				lamportTime := client.local("moderator-lost", fmt.Sprintf("Client %d lost moderator %d", client.id, moderator.ClientId))
+				client.logger.Warn("client lost the moderator", logging.Event, "moderator-lost", logging.Peer, moderator.ClientId, logging.Lamport, lamportTime, "error", err)
+				client.election.mu.Lock()
+				client.election.moderator = nil
+				client.election.mu.Unlock()
+				client.startElection()
+			} else {
+				client.received("heartbeat-reply", participantHost(reply.ClientId), reply.LamportTime, reply.VectorClock, fmt.Sprintf("Client %d got a heartbeat reply from moderator %d", client.id, reply.ClientId))
+			}
+		}
+
+		select {
+		case <-client.stopped:
+			return
+		case <-time.After(heartbeatInterval):
+		}
+	}
+}
+
+func (client *Client) startElection() {
+	e := client.election
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return
+	}
+	e.running = true
+	e.mu.Unlock()
+
+	if e.mode == "ring" {
+		client.startRingElection()
+	} else {
+		client.startBullyElection()
+	}
+
+	e.mu.Lock()
+	e.running = false
+	e.mu.Unlock()
+}
+
+func (client *Client) startBullyElection() {
+	for {
+		lamportTime := client.local("election-start", fmt.Sprintf("Client %d starts a bully election", client.id))
+		client.logger.Info("client starts a bully election", logging.Event, "election-start", logging.Lamport, lamportTime)
+
+		client.election.mu.Lock()
+		announcements := client.election.announcements
+		client.election.mu.Unlock()
+
+		// ask every participant with a higher id; any answer means we lose
+		var wg sync.WaitGroup
+		var answeredMu sync.Mutex
+		answered := false
+		for _, peer := range client.otherParticipants() {
+			if peer.ClientId < int64(client.id) {
+				continue
+			}
+			wg.Add(1)
+			go func(peer *proto.ClientInfo) {
+				defer wg.Done()
+
+				peerClient, err := client.participant(peer)
+				if err != nil {
+					return
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), electionTimeout)
+				defer cancel()
+				lamportTime, vectorClock := client.send("election", participantHost(peer.ClientId), fmt.Sprintf("Client %d sends election message to Participant %d", client.id, peer.ClientId))
+				reply, err := peerClient.Election(ctx, &proto.ClientInfo{
+					ClientId:    int64(client.id),
+					LamportTime: int64(lamportTime),
+					PortNumber:  int64(client.portNumber),
+					Address:     client.address,
+					VectorClock: vectorClock,
+				})
+				if err != nil {
+					return
+				}
+				lamportTime = client.received("election-answer", participantHost(reply.ClientId), reply.LamportTime, reply.VectorClock, fmt.Sprintf("Client %d got an election answer from Participant %d", client.id, reply.ClientId))
+				client.logger.Info("client got an election answer", logging.Event, "election-answer", logging.Peer, reply.ClientId, logging.Lamport, lamportTime)
+
+				answeredMu.Lock()
+				answered = true
+				answeredMu.Unlock()
+			}(peer)
+		}
+		wg.Wait()
+
+		if !answered {
+			client.becomeModerator()
+			return
+		}
+
+		// a higher participant took over; wait for its announcement
+		select {
+		case <-client.stopped:
+			return
+		case <-time.After(electionTimeout):
+		}
+		client.election.mu.Lock()
+		announced := client.election.announcements != announcements
+		client.election.mu.Unlock()
+		if announced {
+			return
+		}
+	}
+}
+
+func (client *Client) startRingElection() {
+	lamportTime := client.local("election-start", fmt.Sprintf("Client %d starts a ring election", client.id))
+	client.logger.Info("client starts a ring election", logging.Event, "election-start", logging.Lamport, lamportTime)
+
+	client.forwardRing(&proto.ElectionInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		Candidates:  []int64{int64(client.id)},
+	})
+}
+
+// forwardRing passes the election message to the next reachable participant in id order
+func (client *Client) forwardRing(in *proto.ElectionInfo) {
+	peers := client.otherParticipants()
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ClientId < peers[j].ClientId })
+
+	// rotate so that the participants after us in the ring come first
+	next := sort.Search(len(peers), func(i int) bool { return peers[i].ClientId > int64(client.id) })
+	ring := append(peers[next:], peers[:next]...)
+
+	for _, peer := range ring {
+		peerClient, err := client.participant(peer)
+		if err != nil {
+			client.logger.Warn("client skips unreachable participant in the ring", logging.Event, "ring-election", logging.Peer, peer.ClientId, "error", err)
+			continue
+		}
+		lamportTime, vectorClock := client.send("ring-election", participantHost(peer.ClientId), fmt.Sprintf("Client %d passes candidates %v to Participant %d", client.id, in.Candidates, peer.ClientId))
+		ctx, cancel := context.WithTimeout(context.Background(), electionTimeout)
+		reply, err := peerClient.RingElection(ctx, &proto.ElectionInfo{
+			ClientId:    in.ClientId,
+			SenderId:    int64(client.id),
+			LamportTime: int64(lamportTime),
+			Candidates:  in.Candidates,
+			VectorClock: vectorClock,
+		})
+		cancel()
+		if err != nil {
+			client.logger.Warn("client skips unreachable participant in the ring", logging.Event, "ring-election", logging.Peer, peer.ClientId, "error", err)
+			continue
+		}
+		client.received("ring-election-ack", participantHost(peer.ClientId), reply.LamportTime, reply.VectorClock, fmt.Sprintf("Participant %d got the ring election message", peer.ClientId))
+		client.logger.Info("client passed the ring election on", logging.Event, "ring-election", logging.Peer, peer.ClientId, logging.Lamport, lamportTime)
+		return
+	}
+
+	// nobody else is reachable
+	client.becomeModerator()
+}
+
+// becomeModerator announces this participant as moderator to everyone else
+func (client *Client) becomeModerator() {
+	self := &proto.ClientInfo{ClientId: int64(client.id), PortNumber: int64(client.portNumber), Address: client.address}
+	client.election.mu.Lock()
+	client.election.moderator = self
+	client.election.mu.Unlock()
+
+	lamportTime := client.local("moderator", fmt.Sprintf("Client %d is the moderator", client.id))
+	client.logger.Info("client is the moderator", logging.Event, "moderator", logging.Lamport, lamportTime)
+	client.announceModerator(self)
+}
+
+// announceModerator broadcasts the election result to every other participant
+func (client *Client) announceModerator(moderator *proto.ClientInfo) {
+	for _, peer := range client.otherParticipants() {
+		peerClient, err := client.participant(peer)
+		if err != nil {
+			continue
+		}
+		lamportTime, vectorClock := client.send("coordinator", participantHost(peer.ClientId), fmt.Sprintf("Client %d announces Participant %d as moderator", client.id, moderator.ClientId))
+		ctx, cancel := context.WithTimeout(context.Background(), electionTimeout)
+		reply, err := peerClient.Coordinator(ctx, &proto.ElectionInfo{
+			ClientId:         int64(client.id),
+			SenderId:         int64(client.id),
+			LamportTime:      int64(lamportTime),
+			ModeratorId:      moderator.ClientId,
+			ModeratorPort:    moderator.PortNumber,
+			ModeratorAddress: moderator.Address,
+			VectorClock:      vectorClock,
+		})
+		cancel()
+		if err != nil {
+			continue
+		}
+		client.received("coordinator-ack", participantHost(peer.ClientId), reply.LamportTime, reply.VectorClock, fmt.Sprintf("Participant %d acknowledged the moderator", peer.ClientId))
+	}
+}
+
+// when a participant with a lower id starts a bully election
+func (client *Client) Election(ctx context.Context, in *proto.ClientInfo) (*proto.ClientInfo, error) {
+	lamportTime := client.received("election", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d received election message from Participant %d", client.id, in.ClientId))
+	client.logger.Info("client received election message", logging.Event, "election", logging.Peer, in.ClientId, logging.Lamport, lamportTime)
+	if client.election == nil {
+		return nil, client.errNoElections()
+	}
+
+	// we outrank the sender, so take over the election
+	go client.startElection()
+
+	lamportTime, vectorClock := client.send("election-answer", participantHost(in.ClientId), fmt.Sprintf("Client %d answers the election message from Participant %d", client.id, in.ClientId))
+	return &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
+
+// when the ring election message reaches this participant
+func (client *Client) RingElection(ctx context.Context, in *proto.ElectionInfo) (*proto.ClientInfo, error) {
+	lamportTime := client.received("ring-election", participantHost(in.SenderId), in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d received candidates %v", client.id, in.Candidates))
+	client.logger.Info("client received ring election message", logging.Event, "ring-election", logging.Peer, in.SenderId, "candidates", in.Candidates, logging.Lamport, lamportTime)
+	if client.election == nil {
+		return nil, client.errNoElections()
+	}
+
+	go client.continueRing(in)
+
+	lamportTime, vectorClock := client.send("ring-election-ack", participantHost(in.SenderId), fmt.Sprintf("Client %d acknowledges the ring election message", client.id))
+	return &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
+
+// continueRing either finishes the election, if the message has gone all the way
+// round, or adds us as a candidate and passes it on
+func (client *Client) continueRing(in *proto.ElectionInfo) {
+	winner := int64(-1)
+	for _, candidate := range in.Candidates {
+		if candidate == int64(client.id) {
+			for _, candidate := range in.Candidates {
+				if candidate > winner {
+					winner = candidate
+				}
+			}
+			break
+		}
+	}
+
+	if winner < 0 {
+		client.forwardRing(&proto.ElectionInfo{
+			ClientId:   in.ClientId,
+			Candidates: append(in.Candidates, int64(client.id)),
+		})
+		return
+	}
+
+	if winner == int64(client.id) {
+		client.becomeModerator()
+		return
+	}
+	for _, peer := range client.otherParticipants() {
+		if peer.ClientId == winner {
+			client.election.mu.Lock()
+			client.election.moderator = peer
+			client.election.announcements++
+			client.election.mu.Unlock()
+
+			lamportTime := client.local("election-end", fmt.Sprintf("Client %d finds Participant %d won the ring election", client.id, winner))
+			client.logger.Info("client announces the ring election winner", logging.Event, "election-end", logging.Participant, winner, logging.Lamport, lamportTime)
+			client.announceModerator(peer)
+			return
+		}
+	}
+}
+
+// when the result of an election is announced
+func (client *Client) Coordinator(ctx context.Context, in *proto.ElectionInfo) (*proto.ClientInfo, error) {
+	lamportTime := client.received("coordinator", participantHost(in.SenderId), in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d acknowledges Participant %d as moderator", client.id, in.ModeratorId))
+	client.logger.Info("client acknowledges the moderator", logging.Event, "coordinator", logging.Participant, in.ModeratorId, logging.Peer, in.SenderId, logging.Lamport, lamportTime)
+	if client.election == nil {
+		return nil, client.errNoElections()
+	}
+
+	client.election.mu.Lock()
+	client.election.moderator = &proto.ClientInfo{ClientId: in.ModeratorId, PortNumber: in.ModeratorPort, Address: in.ModeratorAddress}
+	client.election.announcements++
+	client.election.mu.Unlock()
+
+	lamportTime, vectorClock := client.send("coordinator-ack", participantHost(in.SenderId), fmt.Sprintf("Client %d acknowledges the moderator", client.id))
+	return &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
+
+// errNoElections is returned to election calls by a participant started without -election
+func (client *Client) errNoElections() error {
+	return status.Errorf(grpccodes.FailedPrecondition, "participant %d does not take part in elections", client.id)
+}
+
+// when a participant checks that the moderator is still alive
+func (client *Client) Heartbeat(ctx context.Context, in *proto.ClientInfo) (*proto.ClientInfo, error) {
+	client.received("heartbeat", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d got a heartbeat from Participant %d", client.id, in.ClientId))
+
+	lamportTime, vectorClock := client.send("heartbeat-reply", participantHost(in.ClientId), fmt.Sprintf("Client %d answers the heartbeat of Participant %d", client.id, in.ClientId))
+	return &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
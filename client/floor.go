@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+)
+
+// Ricart-Agrawala mutual exclusion for "the floor": a participant in mutex mode
+// asks every other participant for permission and only publishes once all of
+// them have replied. Replies are deferred simply by not returning from
+// RequestFloor until the floor is free. Competing requests are ordered by the
+// Lamport time the floor was wanted at, which every request carries as its
+// requestTime next to the Lamport time of its own send. The holder tells the
+// server, which rejects the publishes of everyone else until it is released.
+
+type floorState int
+
+const (
+	released floorState = iota
+	wanted
+	held
+)
+
+type floor struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	state       floorState
+	requestTime int // Lamport time of our outstanding request
+}
+
+func newFloor() *floor {
+	f := &floor{state: released}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// defers reports whether a request from another participant has to wait for us.
+// Ties on the timestamp are broken by client id. Must be called with f.mu held.
+func (f *floor) defers(clientID int, in *proto.ClientInfo) bool {
+	switch f.state {
+	case held:
+		return true
+	case wanted:
+		return f.requestTime < int(in.RequestTime) ||
+			(f.requestTime == int(in.RequestTime) && clientID < int(in.ClientId))
+	}
+	return false
+}
+
+// when another participant asks for the floor
+func (client *Client) RequestFloor(ctx context.Context, in *proto.ClientInfo) (*proto.ClientInfo, error) {
+	lamportTime := client.received("floor-request", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d received floor request from Participant %d", client.id, in.ClientId))
+	client.logger.Info("client received floor request", logging.Event, "floor-request", logging.Peer, in.ClientId, "requested_at", in.RequestTime, logging.Lamport, lamportTime)
+
+	// Participants not in mutex mode never want the floor and reply at once
+	if client.floor != nil {
+		f := client.floor
+		f.mu.Lock()
+		for f.defers(client.id, in) {
+			f.cond.Wait()
+		}
+		f.mu.Unlock()
+	}
+
+	lamportTime, vectorClock := client.send("floor-reply", participantHost(in.ClientId), fmt.Sprintf("Client %d replies to floor request from Participant %d", client.id, in.ClientId))
+	client.logger.Info("client replies to floor request", logging.Event, "floor-reply", logging.Peer, in.ClientId, logging.Lamport, lamportTime)
+
+	return &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
+
+// acquireFloor blocks until every other participant has replied to our
+// request and the server knows that we hold the floor. A participant that does
+// not reply within the RPC timeout fails it.
+func (client *Client) acquireFloor() error {
+	f := client.floor
+	peers := client.otherParticipants()
+
+	f.mu.Lock()
+	f.state = wanted
+	f.requestTime = client.local("floor-wanted", fmt.Sprintf("Client %d wants the floor", client.id))
+	requestTime := f.requestTime
+	f.mu.Unlock()
+
+	client.logger.Info("client requests the floor", logging.Event, "floor-request", logging.Lamport, requestTime)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(peers))
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *proto.ClientInfo) {
+			defer wg.Done()
+
+			peerClient, err := client.participant(peer)
+			if err != nil {
+				client.logger.Warn("participant unreachable, counting it as a floor reply", logging.Event, "floor-reply", logging.Peer, peer.ClientId, "error", err)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+			defer cancel()
+
+			lamportTime, vectorClock := client.send("floor-request", participantHost(peer.ClientId), fmt.Sprintf("Client %d requests the floor from Participant %d", client.id, peer.ClientId))
+			reply, err := peerClient.RequestFloor(ctx, &proto.ClientInfo{
+				ClientId:    int64(client.id),
+				LamportTime: int64(lamportTime),
+				RequestTime: int64(requestTime),
+				PortNumber:  int64(client.portNumber),
+				Address:     client.address,
+				VectorClock: vectorClock,
+			})
+			if status.Code(err) == grpccodes.DeadlineExceeded {
+				client.local("floor-timeout", fmt.Sprintf("Client %d got no floor reply from Participant %d in time", client.id, peer.ClientId))
+				errs[i] = fmt.Errorf("participant %d did not reply to the floor request in time", peer.ClientId)
+				return
+			}
+			if err != nil {
+				// a participant that has left cannot object
+				client.logger.Warn("participant unreachable, counting it as a floor reply", logging.Event, "floor-reply", logging.Peer, peer.ClientId, "error", err)
+				return
+			}
+
+			lamportTime = client.received("floor-reply", participantHost(reply.ClientId), reply.LamportTime, reply.VectorClock, fmt.Sprintf("Client %d received floor reply from Participant %d", client.id, reply.ClientId))
+			client.logger.Info("client received floor reply", logging.Event, "floor-reply", logging.Peer, reply.ClientId, logging.Lamport, lamportTime)
+		}(i, peer)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		client.giveUpFloor()
+		return err
+	}
+
+	f.mu.Lock()
+	f.state = held
+	f.mu.Unlock()
+
+	lamportTime := client.local("floor-held", fmt.Sprintf("Client %d holds the floor", client.id))
+	client.logger.Info("client holds the floor", logging.Event, "floor-held", logging.Lamport, lamportTime)
+
+	if err := client.tellFloor("floor-take", "takes", client.server.TakeFloor); err != nil {
+		client.giveUpFloor()
+		return err
+	}
+	return nil
+}
+
+// releaseFloor tells the server that the floor is free and answers every deferred request
+func (client *Client) releaseFloor() {
+	if err := client.tellFloor("floor-release", "releases", client.server.ReleaseFloor); err != nil {
+		client.logger.Warn("server did not take back the floor", logging.Event, "floor-release", "error", err)
+	}
+	client.giveUpFloor()
+}
+
+// tellFloor tells the server that we take or release the floor
+func (client *Client) tellFloor(kind, verb string, call func(context.Context, *proto.ClientInfo, ...grpc.CallOption) (*proto.ServerInfo, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send(kind, serverHost, fmt.Sprintf("Client %d %s the floor at the server", client.id, verb))
+	_, err := call(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	})
+	return err
+}
+
+// giveUpFloor answers every deferred request
+func (client *Client) giveUpFloor() {
+	f := client.floor
+
+	f.mu.Lock()
+	f.state = released
+	f.mu.Unlock()
+	f.cond.Broadcast()
+
+	lamportTime := client.local("floor-released", fmt.Sprintf("Client %d releases the floor", client.id))
+	client.logger.Info("client releases the floor", logging.Event, "floor-released", logging.Lamport, lamportTime)
+}
+
+// otherParticipants asks the server who else is in the chat
+func (client *Client) otherParticipants() []*proto.ClientInfo {
+	participants, err := client.participants()
+	if err != nil {
+		return nil
+	}
+
+	others := make([]*proto.ClientInfo, 0, len(participants))
+	for _, participant := range participants {
+		if participant.ClientId != int64(client.id) {
+			others = append(others, participant)
+		}
+	}
+	return others
+}
+
+// participants asks the server who is in the chat, including this participant
+func (client *Client) participants() ([]*proto.ClientInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("participants", serverHost, fmt.Sprintf("Client %d asks for the participant list", client.id))
+	list, err := client.server.Participants(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		client.logger.Error("client could not get the participant list", logging.Event, "participants", "error", err)
+		return nil, err
+	}
+	lamportTime = client.received("participants-reply", serverHost, list.LamportTime, list.VectorClock, fmt.Sprintf("Client %d got %d participants", client.id, len(list.Participants)))
+	return list.Participants, nil
+}
+
+// participant returns a (cached) connection to another participant's
+// ParticipantService. There is none once the participant stopped: its event
+// log is closed, so the calls of a goroutine still running would go unlogged.
+func (client *Client) participant(peer *proto.ClientInfo) (proto.ParticipantServiceClient, error) {
+	client.peersMu.Lock()
+	defer client.peersMu.Unlock()
+
+	select {
+	case <-client.stopped:
+		return nil, fmt.Errorf("participant %d has stopped", client.id)
+	default:
+	}
+
+	if conn, ok := client.peers[peer.ClientId]; ok {
+		return proto.NewParticipantServiceClient(conn), nil
+	}
+	conn, err := grpc.Dial(participantAddress(peer), client.dialOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %w", participantAddress(peer), err)
+	}
+	client.peers[peer.ClientId] = conn
+	return proto.NewParticipantServiceClient(conn), nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics of a participant, served when it is started with -metrics
+
+var (
+	publishes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_client_publishes_total",
+		Help: "Messages published by this participant.",
+	})
+	failedPublishes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_client_failed_publishes_total",
+		Help: "Messages this participant could not publish.",
+	})
+	duplicateDeliveries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_client_duplicate_deliveries_total",
+		Help: "Copies of messages received before, which were dropped.",
+	})
+	broadcastsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chitty_client_broadcasts_received_total",
+		Help: "Broadcasts received from the server, by kind.",
+	}, []string{"kind"})
+)
+
+// registerClock exports the participant's Lamport time as a gauge. With several
+// participants in one process only the first one started with metrics is exported:
+// the gauge is returned to be unregistered on Stop, or nil for the others.
+func registerClock(client *Client) prometheus.Collector {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "chitty_lamport_time",
+		Help: "Current Lamport time of the participant.",
+	}, func() float64 { return float64(client.clock()) })
+	if err := prometheus.Register(gauge); err != nil {
+		return nil // another one is exported already
+	}
+	return gauge
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+)
+
+// A participant started with -read-receipts tells the server when it has shown
+// a message published by another participant, and the server tells the
+// publisher. Every participant shows the receipts for its own messages, whether
+// it sends receipts or not.
+
+// wantsReceipt reports whether a read receipt is sent for a received message:
+// only for messages published by others with a message id, not for direct
+// messages or announcements
+func (client *Client) wantsReceipt(in *proto.ClientInfo) bool {
+	return client.opts.Receipts && in.MessageId != "" && !in.Announcement && in.RecipientId == 0 && in.ClientId != int64(client.id)
+}
+
+// sendReadReceipt tells the server that this participant has read a message
+func (client *Client) sendReadReceipt(in *proto.ClientInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.opts.Timeouts.RPC)
+	defer cancel()
+
+	lamportTime, vectorClock := client.send("read", serverHost, fmt.Sprintf("Client %d read message %s of Participant %d", client.id, in.MessageId, in.ClientId))
+	client.logger.Debug("client sends read receipt", logging.Event, "read", logging.Participant, in.ClientId, logging.MessageID, in.MessageId, logging.Lamport, lamportTime)
+	_, err := client.server.MessageRead(ctx, &proto.ClientInfo{
+		ClientId:    int64(client.id),
+		LamportTime: int64(lamportTime),
+		Message:     in.Message,
+		RecipientId: in.ClientId,
+		MessageId:   in.MessageId,
+		VectorClock: vectorClock,
+	})
+	if err != nil {
+		client.logger.Warn("client could not send read receipt", logging.Event, "read", logging.Participant, in.ClientId, logging.MessageID, in.MessageId, "error", err)
+	}
+}
+
+// when another participant has read a message this participant published
+func (client *Client) ClientReadReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := client.received("read-receipt", serverHost, in.LamportTime, in.VectorClock, fmt.Sprintf("Client %d learns that Participant %d read message %s", client.id, in.ClientId, in.MessageId))
+
+	client.logger.Info("message read", logging.Event, "read-receipt", logging.Participant, in.ClientId, logging.MessageID, in.MessageId, logging.Lamport, lamportTime, "message", in.Message)
+	client.notify(readMsg{reader: in.ClientId, nickname: in.Nickname, lamport: lamportTime, text: in.Message})
+
+	return &proto.ServerInfo{
+		LamportTime: int64(lamportTime),
+	}, nil
+}
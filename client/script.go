@@ -0,0 +1,92 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"os"
+	"strings"
+	"time"
+)
+
+// A script lets a participant run without a terminal, e.g. in the docker
+// compose demo. Every line holds one action; blank lines and lines starting
+// with # are skipped:
+//
+//	wait 2s
+//	say hello everyone
+//	leave
+//
+// The participant leaves when the script ends, so the final leave is optional.
+
+// Action is one line of a script.
+type Action struct {
+	Kind  string // wait, say or leave
+	Delay time.Duration
+	Text  string
+}
+
+// ReadScript parses the script at path.
+func ReadScript(path string) ([]Action, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	script := []Action{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if len(script) > 0 && script[len(script)-1].Kind == "leave" {
+			return nil, fmt.Errorf("%s:%d: action after leave", path, line)
+		}
+
+		kind, arg, _ := strings.Cut(text, " ")
+		arg = strings.TrimSpace(arg)
+		switch kind {
+		case "wait":
+			delay, err := time.ParseDuration(arg)
+			if err != nil || delay < 0 {
+				return nil, fmt.Errorf("%s:%d: wait needs a duration such as 2s, got %q", path, line, arg)
+			}
+			script = append(script, Action{Kind: kind, Delay: delay})
+		case "say":
+			if arg == "" {
+				return nil, fmt.Errorf("%s:%d: say needs a message", path, line)
+			}
+			script = append(script, Action{Kind: kind, Text: arg})
+		case "leave":
+			if arg != "" {
+				return nil, fmt.Errorf("%s:%d: leave takes no argument", path, line)
+			}
+			script = append(script, Action{Kind: kind})
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown action %q, use wait, say or leave", path, line, kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return script, nil
+}
+
+// RunScript runs the actions in order and returns when the participant should leave.
+func (client *Client) RunScript(script []Action) {
+	for _, action := range script {
+		switch action.Kind {
+		case "wait":
+			client.logger.Debug("client waits", logging.Event, "script", "delay", action.Delay)
+			time.Sleep(action.Delay)
+		case "say":
+			client.Publish(action.Text)
+		case "leave":
+			return
+		}
+	}
+}
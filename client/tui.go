@@ -0,0 +1,318 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"google.golang.org/grpc/status"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The terminal UI (-tui) shows the chat in a scrollback pane with the sender
+// and Lamport time of every message, the participants in a sidebar kept up to
+// date from the join and leave broadcasts, and an input line that enforces the
+// 128 character limit while typing. After a publish it shows how many
+// participants acknowledged the message, and later who read it, if they send
+// read receipts. Lines starting with / are the commands registered in commands.go.
+
+const sidebarWidth = 18
+
+var (
+	lamportStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	senderStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	selfStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
+	directStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
+	announcementStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
+	systemStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Italic(true)
+	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	sidebarStyle      = lipgloss.NewStyle().Width(sidebarWidth).PaddingLeft(1).
+				Border(lipgloss.NormalBorder(), false, false, false, true)
+)
+
+// messages sent to the UI by the ParticipantService handlers and by commands
+type (
+	chatMsg struct {
+		from         int64
+		nickname     string
+		lamport      int
+		text         string
+		announcement bool
+		direct       bool
+	}
+	joinedMsg struct {
+		id      int64
+		lamport int
+	}
+	leftMsg struct {
+		id      int64
+		lamport int
+		reason  string
+	}
+	deliveredMsg struct {
+		delivered, recipients int64
+	}
+	readMsg struct {
+		reader   int64
+		nickname string
+		lamport  int
+		text     string
+	}
+	participantsMsg struct {
+		ids  []int64
+		show bool // print the list, not only update the sidebar
+	}
+	systemMsg string
+	errorMsg  struct{ err error }
+	kickedMsg struct{}
+)
+
+// notify shows msg in the terminal UI, if it is running
+func (client *Client) notify(msg tea.Msg) {
+	if client.tui != nil {
+		client.tui.Send(msg)
+	}
+}
+
+// RunTUI starts the participant and runs the terminal UI until the user leaves
+// or is kicked out. Log lines would garble the screen, so the caller should
+// send the log elsewhere first; the UI shows what matters.
+func (client *Client) RunTUI() error {
+	client.tui = tea.NewProgram(newModel(client), tea.WithAltScreen())
+	go func() {
+		select {
+		case <-client.kicked:
+			client.tui.Send(kickedMsg{})
+		case <-client.stopped:
+		}
+	}()
+
+	_, err := client.tui.Run()
+	return err
+}
+
+type model struct {
+	client       *Client
+	scrollback   viewport.Model
+	input        textinput.Model
+	lines        []string
+	participants map[int64]bool
+	ready        bool
+}
+
+func newModel(client *Client) model {
+	input := textinput.New()
+	input.Placeholder = "Say something, or /help for the commands"
+	input.CharLimit = 128
+	input.Focus()
+	return model{
+		client:       client,
+		input:        input,
+		participants: map[int64]bool{},
+	}
+}
+
+func (m model) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, m.join)
+}
+
+// join runs in its own goroutine, like every command that calls the server
+func (m model) join() tea.Msg {
+	if err := m.client.Start(); err != nil {
+		return errorMsg{fmt.Errorf("could not join: %v", err)}
+	}
+	return m.who(false)()
+}
+
+func (m model) who(show bool) tea.Cmd {
+	return func() tea.Msg {
+		participants, err := m.client.participants()
+		if err != nil {
+			return errorMsg{fmt.Errorf("could not get the participants: %v", err)}
+		}
+		ids := make([]int64, 0, len(participants))
+		for _, participant := range participants {
+			ids = append(ids, participant.ClientId)
+		}
+		return participantsMsg{ids: ids, show: show}
+	}
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		width, height := msg.Width-sidebarWidth-2, msg.Height-2
+		if !m.ready {
+			m.scrollback = viewport.New(width, height)
+			m.ready = true
+		} else {
+			m.scrollback.Width, m.scrollback.Height = width, height
+		}
+		m.input.Width = msg.Width - 12
+		m.refresh()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			return m, tea.Quit
+		case tea.KeyEnter:
+			line := m.input.Value()
+			m.input.Reset()
+			return m, m.submit(line)
+		case tea.KeyPgUp, tea.KeyPgDown:
+			var cmd tea.Cmd
+			m.scrollback, cmd = m.scrollback.Update(msg)
+			return m, cmd
+		}
+
+	case chatMsg:
+		lamport := lamportStyle.Render(fmt.Sprintf("[L%d]", msg.lamport))
+		switch {
+		case msg.announcement:
+			m.add(lamport + " " + announcementStyle.Render("*** "+msg.text))
+		case msg.direct:
+			m.add(lamport + " " + directStyle.Render(fmt.Sprintf("%s → you: %s", displayName(msg.from, msg.nickname), msg.text)))
+		default:
+			m.add(fmt.Sprintf("%s %s %s", lamport, m.sender(msg.from, msg.nickname), msg.text))
+		}
+		return m, nil
+
+	case deliveredMsg:
+		m.add(systemStyle.Render(fmt.Sprintf("delivered to %d/%d participants", msg.delivered, msg.recipients)))
+		return m, nil
+
+	case readMsg:
+		m.add(lamportStyle.Render(fmt.Sprintf("[L%d]", msg.lamport)) + " " + systemStyle.Render(fmt.Sprintf("%s read %q", displayName(msg.reader, msg.nickname), msg.text)))
+		return m, nil
+
+	case joinedMsg:
+		m.participants[msg.id] = true
+		m.add(lamportStyle.Render(fmt.Sprintf("[L%d]", msg.lamport)) + " " + systemStyle.Render(participantHost(msg.id)+" joined"))
+		return m, nil
+
+	case leftMsg:
+		delete(m.participants, msg.id)
+		m.add(lamportStyle.Render(fmt.Sprintf("[L%d]", msg.lamport)) + " " + systemStyle.Render(participantHost(msg.id)+" "+msg.reason))
+		return m, nil
+
+	case participantsMsg:
+		m.participants = map[int64]bool{}
+		names := make([]string, 0, len(msg.ids))
+		for _, id := range msg.ids {
+			m.participants[id] = true
+			names = append(names, participantHost(id))
+		}
+		if msg.show {
+			m.add(systemStyle.Render(fmt.Sprintf("%d in the chat: %s", len(names), strings.Join(names, ", "))))
+		}
+		return m, nil
+
+	case systemMsg:
+		m.add(systemStyle.Render(string(msg)))
+		return m, nil
+
+	case errorMsg:
+		m.add(errorStyle.Render(status.Convert(msg.err).Message())) // without the "rpc error: code = ..." prefix
+		return m, nil
+
+	case kickedMsg:
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// submit publishes line or runs it as a command
+func (m model) submit(line string) tea.Cmd {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if !strings.HasPrefix(line, "/") {
+		return func() tea.Msg {
+			if err := m.client.Publish(line); err != nil {
+				return errorMsg{err}
+			}
+			return nil
+		}
+	}
+
+	return func() tea.Msg {
+		output, err := m.client.RunCommand(line)
+		switch {
+		case errors.Is(err, errLeave):
+			return tea.Quit()
+		case err != nil:
+			return errorMsg{err}
+		case output != "":
+			return systemMsg(output)
+		}
+		return nil
+	}
+}
+
+func (m *model) sender(id int64, nickname string) string {
+	if id == int64(m.client.id) {
+		return selfStyle.Render(displayName(id, nickname) + ":")
+	}
+	return senderStyle.Render(displayName(id, nickname) + ":")
+}
+
+// add appends a line to the scrollback and scrolls to it
+func (m *model) add(line string) {
+	m.lines = append(m.lines, line)
+	m.refresh()
+}
+
+func (m *model) refresh() {
+	if !m.ready {
+		return
+	}
+	wrap := lipgloss.NewStyle().Width(m.scrollback.Width)
+	wrapped := make([]string, len(m.lines))
+	for i, line := range m.lines {
+		wrapped[i] = wrap.Render(line)
+	}
+	m.scrollback.SetContent(strings.Join(wrapped, "\n"))
+	m.scrollback.GotoBottom()
+}
+
+func (m model) View() string {
+	if !m.ready {
+		return "Connecting..."
+	}
+
+	ids := make([]int64, 0, len(m.participants))
+	for id := range m.participants {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var sidebar strings.Builder
+	sidebar.WriteString(lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("Participants (%d)", len(ids))))
+	for _, id := range ids {
+		name := participantHost(id)
+		if id == int64(m.client.id) {
+			name = selfStyle.Render(name + " (you)")
+		}
+		sidebar.WriteString("\n" + name)
+	}
+
+	// the counter turns red for input the server would reject
+	value := m.input.Value()
+	counter := lamportStyle.Render(fmt.Sprintf("%3d/128", utf8.RuneCountInString(value)))
+	if !validMessage(value) {
+		counter = errorStyle.Render(fmt.Sprintf("%3d/128", utf8.RuneCountInString(value)))
+	}
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.scrollback.View(),
+		sidebarStyle.Height(m.scrollback.Height).Render(sidebar.String()),
+	)
+	return body + "\n" + m.input.View() + " " + counter
+}
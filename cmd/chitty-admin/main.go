@@ -0,0 +1,155 @@
+// chitty-admin calls the AdminService of a running Chitty-Chat server. The
+// server has to be started with -admin-token, and the same token has to be
+// given here with -token or in CHITTY_ADMIN_TOKEN.
+//
+//	chitty-admin -server localhost:5454 list
+//	chitty-admin kick 3
+//	chitty-admin broadcast "Server restarts in 5 minutes"
+//	chitty-admin clock
+//	chitty-admin dump
+//	chitty-admin log-level debug
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	serverAddr = flag.String("server", "localhost:5454", "address of the server")
+	useTLS     = flag.Bool("tls", false, "dial the server with TLS")
+	caFile     = flag.String("ca", "", "PEM file the server's certificate is checked against with -tls (default the system's)")
+	token      = flag.String("token", os.Getenv("CHITTY_ADMIN_TOKEN"), "admin token (default $CHITTY_ADMIN_TOKEN)")
+	timeout    = flag.Duration("timeout", 10*time.Second, "how long to wait for the server")
+)
+
+const usage = `Usage: chitty-admin [-server host:port] [-tls [-ca file]] [-token token] command [argument]
+
+Commands:
+  list               list the participants
+  kick <id>          remove a participant from the chat
+  broadcast <text>   send a system announcement to every participant
+  clock              show the server's Lamport time and vector clock
+  dump               show the server's state as JSON
+  log-level <level>  set the server's log level (debug, info, warn or error)
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage); flag.PrintDefaults() }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	creds, err := config.DialCredentials(*useTLS, *caFile)
+	if err != nil {
+		log.Fatalf("Could not load the CA certificate: %v", err)
+	}
+	conn, err := grpc.Dial(*serverAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Could not connect to %s: %v", *serverAddr, err)
+	}
+	defer conn.Close()
+	admin := proto.NewAdminServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+
+	if err := run(ctx, admin, flag.Arg(0), flag.Args()[1:]); err != nil {
+		log.Fatalf("%s: %v", flag.Arg(0), err)
+	}
+}
+
+func run(ctx context.Context, admin proto.AdminServiceClient, command string, args []string) error {
+	switch command {
+	case "list":
+		list, err := admin.ListParticipants(ctx, &proto.AdminRequest{})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d participants at Lamport time %d\n", len(list.Participants), list.LamportTime)
+		for _, participant := range list.Participants {
+			fmt.Printf("  %s\n", describe(participant))
+		}
+
+	case "kick":
+		if len(args) != 1 {
+			return fmt.Errorf("expected the id of the participant")
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad participant id %q", args[0])
+		}
+		info, err := admin.KickParticipant(ctx, &proto.AdminRequest{ClientId: id})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Kicked participant %d at Lamport time %d\n", id, info.LamportTime)
+
+	case "broadcast":
+		if len(args) == 0 {
+			return fmt.Errorf("expected the announcement")
+		}
+		info, err := admin.Broadcast(ctx, &proto.AdminRequest{Message: strings.Join(args, " ")})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Announced at Lamport time %d, delivered to %d/%d participants\n", info.LamportTime, info.Delivered, info.Recipients)
+
+	case "clock":
+		info, err := admin.GetClock(ctx, &proto.AdminRequest{})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Lamport time %d\nVector clock %v\n", info.LamportTime, info.VectorClock)
+
+	case "dump":
+		state, err := admin.DumpState(ctx, &proto.AdminRequest{})
+		if err != nil {
+			return err
+		}
+		fmt.Println(protojson.MarshalOptions{Multiline: true}.Format(state))
+
+	case "log-level":
+		if len(args) != 1 {
+			return fmt.Errorf("expected the log level")
+		}
+		if _, err := admin.SetLogLevel(ctx, &proto.AdminRequest{LogLevel: args[0]}); err != nil {
+			return err
+		}
+		fmt.Printf("Log level set to %s\n", args[0])
+
+	default:
+		return fmt.Errorf("unknown command, run chitty-admin -h for the list")
+	}
+	return nil
+}
+
+// describe returns the id and address of a participant, and its nickname and room when set
+func describe(participant *proto.ClientInfo) string {
+	address := participant.Address
+	if address == "" {
+		address = "localhost:" + strconv.FormatInt(participant.PortNumber, 10)
+	}
+	description := fmt.Sprintf("participant %d at %s", participant.ClientId, address)
+	if participant.Nickname != "" {
+		description += fmt.Sprintf(", nickname %q", participant.Nickname)
+	}
+	if participant.Room != "" {
+		description += ", room " + participant.Room
+	}
+	return description
+}
@@ -0,0 +1,139 @@
+// chitty-client runs a Chitty-Chat participant that publishes the lines typed
+// in the terminal, runs a script, publishes one message or shows the terminal
+// UI, and leaves on SIGINT or SIGTERM.
+//
+//	chitty-client -cPort 8080 -sPort 5454 -id 1
+//	chitty-client -config config/client.example.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/client"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/tracing"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// The settings of the participant. The flags are bound to its fields and
+// config.Load fills in the rest from the config file and the CHITTY_* environment variables.
+var cfg config.Client
+
+var configPath = flag.String("config", os.Getenv("CHITTY_CONFIG"), "read settings from this YAML file")
+
+func init() {
+	flag.IntVar(&cfg.Port, "cPort", 0, "client port number")
+	flag.StringVar(&cfg.Advertise, "advertise", "", "host:port the server and peers reach this client at (default localhost:cPort)")
+	flag.StringVar(&cfg.Server.Address, "server", "", "server address host:port, overrides -sHost and -sPort")
+	flag.StringVar(&cfg.Server.Host, "sHost", "localhost", "server host name")
+	flag.IntVar(&cfg.Server.Port, "sPort", 0, "server port number (should match the port used for the server)")
+	flag.BoolVar(&cfg.Server.TLS, "tls", false, "dial the server with TLS")
+	flag.StringVar(&cfg.Server.CA, "ca", "", "PEM file the server's certificate is checked against with -tls (default the system's)")
+	flag.IntVar(&cfg.ID, "id", 0, "client ID number")
+	flag.StringVar(&cfg.Script, "script", "", "run the actions in this file (wait 2s, say hello, leave) instead of reading the terminal")
+	flag.StringVar(&cfg.Message, "message", "", "join, publish this message and leave")
+	flag.BoolVar(&cfg.TUI, "tui", false, "run the full-screen terminal UI")
+	flag.BoolVar(&cfg.Mutex, "mutex", false, "request the floor (Ricart-Agrawala) before publishing; while it is held, the server rejects everyone else's publishes")
+	flag.BoolVar(&cfg.Receipts, "read-receipts", false, "send a read receipt to the publisher of every message shown")
+	flag.StringVar(&cfg.Election, "election", "", "elect a moderator among the participants: bully or ring")
+	flag.StringVar(&cfg.Nickname, "nick", "", "nickname to set after joining, like /nick")
+	flag.StringVar(&cfg.Room, "room", "", "room to move to after joining, like /join (default the lobby)")
+	flag.StringVar(&cfg.EventLog, "eventlog", "", "write a vector clock event log (GoVector/ShiViz format) to this file")
+	flag.StringVar(&cfg.Clock, "clock", config.VectorClocks, "timestamps sent with every message: vector (Lamport time and vector clock) or lamport")
+	flag.StringVar(&cfg.Log.Format, "log-format", "text", "log output format: text or json")
+	flag.StringVar(&cfg.Log.Level, "log-level", "info", "minimum log level: debug, info, warn or error")
+	flag.StringVar(&cfg.Metrics, "metrics", "", "serve Prometheus metrics at this address, e.g. :9101")
+	flag.StringVar(&cfg.Trace.Exporter, "trace-exporter", "none", "export OpenTelemetry spans: none, stdout or otlp")
+	flag.StringVar(&cfg.Trace.Endpoint, "trace-endpoint", "localhost:4317", "address of the OTLP collector for -trace-exporter otlp")
+	flag.DurationVar(&cfg.Timeouts.Connect, "connect-timeout", 5*time.Second, "give up joining the server after this long")
+	flag.DurationVar(&cfg.Timeouts.RPC, "rpc-timeout", 10*time.Second, "give up any other call to the server after this long")
+}
+
+func main() {
+	flag.Parse()
+	if err := config.Load(flag.CommandLine, *configPath, &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	// log lines would garble the terminal UI
+	logOutput := io.Writer(os.Stderr)
+	if cfg.TUI {
+		logOutput = io.Discard
+	}
+	if _, err := logging.Setup(logOutput, cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatalf("Could not set up logging: %v", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), fmt.Sprintf("client-%d", cfg.ID), cfg.Trace.Exporter, cfg.Trace.Endpoint)
+	if err != nil {
+		log.Fatalf("Could not set up tracing: %v", err)
+	}
+
+	// Messages are read from the terminal, or from a script when running unattended
+	var script []client.Action
+	switch {
+	case cfg.Message != "":
+		script = []client.Action{{Kind: "say", Text: cfg.Message}}
+	case cfg.Script != "":
+		script, err = client.ReadScript(cfg.Script)
+		if err != nil {
+			log.Fatalf("Could not read the script: %v", err)
+		}
+	}
+
+	participant, err := client.New(client.Options{Client: cfg})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.TUI {
+		// the terminal UI joins, handles signals and being kicked out itself
+		if err := participant.RunTUI(); err != nil {
+			log.Fatalf("Could not run the terminal UI: %v", err)
+		}
+		select {
+		case <-participant.Kicked():
+			fmt.Println("You were removed from the chat by an operator.")
+		default:
+		}
+	} else {
+		if err := participant.Start(); err != nil {
+			log.Fatal(err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			if script != nil {
+				participant.RunScript(script)
+				close(done)
+			} else if participant.ReadInput(os.Stdin, os.Stdout) {
+				close(done)
+			}
+		}()
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+		// Block until a signal is received, the script has run, the user leaves or we are kicked out
+		select {
+		case <-sigChan:
+		case <-done:
+		case <-participant.Kicked():
+		}
+	}
+
+	if err := participant.Stop(); err != nil {
+		slog.Error("could not leave the chat", "error", err)
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("could not flush spans", "error", err)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// gateway keeps the browser sessions, by session token
+type gateway struct {
+	server   proto.CCServiceClient
+	host     string
+	idle     time.Duration // how long a session waits for its browser to open /api/events
+	mu       sync.Mutex    // guards sessions and nextID
+	sessions map[string]*session
+	nextID   int64
+}
+
+func newGateway(server proto.CCServiceClient, host string, firstID int64, idle time.Duration) *gateway {
+	return &gateway{
+		server:   server,
+		host:     host,
+		idle:     idle,
+		sessions: make(map[string]*session),
+		nextID:   firstID,
+	}
+}
+
+func (g *gateway) routes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/join", post(g.join))
+	mux.HandleFunc("/api/messages", post(g.publish))
+	mux.HandleFunc("/api/leave", post(g.leave))
+	mux.Handle("/api/events", websocket.Handler(g.events))
+}
+
+// request is the body of the POST calls; each uses some of the fields
+type request struct {
+	Session  string `json:"session"`
+	Nickname string `json:"nickname"`
+	Message  string `json:"message"`
+}
+
+// reply is the body of a successful POST call
+type reply struct {
+	Session  string `json:"session,omitempty"`
+	ClientID int64  `json:"clientId,omitempty"`
+	Lamport  int64  `json:"lamport"`
+}
+
+// post decodes the JSON request, runs handle with a deadline and encodes its reply or error
+func post(handle func(ctx context.Context, in request) (reply, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		var in request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&in); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad JSON: " + err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), *timeout)
+		defer cancel()
+		out, err := handle(ctx, in)
+		if err != nil {
+			writeJSON(w, httpStatus(err), map[string]string{"error": status.Convert(err).Message()})
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+var errNoSession = status.Error(codes.NotFound, "no such session, join first")
+
+// httpStatus maps the gRPC status of err to an HTTP status code
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.DeadlineExceeded, codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+func (g *gateway) join(ctx context.Context, in request) (reply, error) {
+	// checked before joining, so that a bad nickname does not leave a participant behind
+	if nickname := strings.TrimSpace(in.Nickname); in.Nickname != "" && (nickname == "" || !utf8.ValidString(nickname) || utf8.RuneCountInString(nickname) > 32) {
+		return reply{}, status.Error(codes.InvalidArgument, "a nickname has between 1 and 32 UTF-8 characters")
+	}
+	token, err := newToken()
+	if err != nil {
+		return reply{}, err
+	}
+
+	var s *session
+	for attempt := 1; ; attempt++ {
+		id, err := g.freeID(ctx)
+		if err != nil {
+			return reply{}, err
+		}
+		if s, err = startSession(g.server, id, g.host); err != nil {
+			return reply{}, err
+		}
+		err = s.join(ctx, in.Nickname)
+		if err == nil {
+			break
+		}
+		s.stop()
+		// someone else joined with the id since it was checked
+		if status.Code(err) != codes.AlreadyExists || attempt == joinAttempts {
+			return reply{}, err
+		}
+	}
+	id := s.id
+
+	g.mu.Lock()
+	g.sessions[token] = s
+	s.idle = time.AfterFunc(g.idle, func() {
+		if g.session(token) == s {
+			slog.Info("browser did not open its events", "client", id)
+			g.drop(token)
+		}
+	})
+	g.mu.Unlock()
+	slog.Info("browser joined", "client", id, "address", s.address)
+	return reply{Session: token, ClientID: id, Lamport: s.clock()}, nil
+}
+
+// joinAttempts is how many ids a session tries when others take them first
+const joinAttempts = 3
+
+// freeID returns the next session id that no participant in the chat has,
+// e.g. a client started with -id
+func (g *gateway) freeID(ctx context.Context) (int64, error) {
+	g.mu.Lock()
+	id := g.nextID
+	g.mu.Unlock()
+
+	list, err := g.server.Participants(ctx, &proto.ClientInfo{ClientId: id})
+	if err != nil {
+		return 0, err
+	}
+	taken := make(map[int64]bool, len(list.Participants))
+	for _, participant := range list.Participants {
+		taken[participant.ClientId] = true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for taken[g.nextID] {
+		g.nextID++
+	}
+	id = g.nextID
+	g.nextID++
+	return id, nil
+}
+
+func (g *gateway) publish(ctx context.Context, in request) (reply, error) {
+	s := g.session(in.Session)
+	if s == nil {
+		return reply{}, errNoSession
+	}
+	if !utf8.ValidString(in.Message) || in.Message == "" || utf8.RuneCountInString(in.Message) > 128 {
+		return reply{}, status.Error(codes.InvalidArgument, "send a message of UTF-8 and within 128 characters in length")
+	}
+	if err := s.publish(ctx, in.Message); err != nil {
+		return reply{}, err
+	}
+	return reply{Lamport: s.clock()}, nil
+}
+
+func (g *gateway) leave(ctx context.Context, in request) (reply, error) {
+	s := g.remove(in.Session)
+	if s == nil {
+		return reply{}, errNoSession
+	}
+	err := s.leave(ctx)
+	return reply{Lamport: s.clock()}, err
+}
+
+// events streams the broadcasts a session receives. The session leaves the chat when the stream closes.
+func (g *gateway) events(ws *websocket.Conn) {
+	token := ws.Request().URL.Query().Get("session")
+	s := g.session(token)
+	if s == nil {
+		websocket.JSON.Send(ws, event{Type: "error", Message: "no such session, join first"})
+		return
+	}
+	s.idle.Stop()
+
+	// the browser does not send anything, a failed read means it went away
+	closed := make(chan struct{})
+	go func() {
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		close(closed)
+	}()
+
+	for {
+		select {
+		case e := <-s.events:
+			if err := websocket.JSON.Send(ws, e); err != nil {
+				g.drop(token)
+				return
+			}
+		case <-s.done:
+			// deliver what arrived before the session stopped, e.g. that it was kicked
+			for len(s.events) > 0 {
+				websocket.JSON.Send(ws, <-s.events)
+			}
+			g.remove(token)
+			return
+		case <-closed:
+			g.drop(token)
+			return
+		}
+	}
+}
+
+// drop makes a session whose browser went away leave the chat
+func (g *gateway) drop(token string) {
+	if s := g.remove(token); s != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		if err := s.leave(ctx); err != nil {
+			slog.Warn("could not leave for a closed browser", "client", s.id, "error", err)
+		}
+	}
+}
+
+func (g *gateway) session(token string) *session {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.sessions[token]
+}
+
+func (g *gateway) remove(token string) *session {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := g.sessions[token]
+	delete(g.sessions, token)
+	return s
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not create a session token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
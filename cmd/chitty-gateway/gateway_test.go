@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer records who is in the chat. Calls the gateway does not make are
+// left to the embedded nil client and panic.
+type fakeServer struct {
+	proto.CCServiceClient
+
+	mu          sync.Mutex
+	joined      map[int64]bool
+	published   []*proto.ClientInfo
+	nicknameErr error // returned by SetNickname
+}
+
+func (f *fakeServer) ParticipantJoins(ctx context.Context, in *proto.ClientInfo, opts ...grpc.CallOption) (*proto.ServerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.joined[in.ClientId] {
+		return nil, status.Errorf(codes.AlreadyExists, "participant %d is already in the chat", in.ClientId)
+	}
+	f.joined[in.ClientId] = true
+	return &proto.ServerInfo{LamportTime: in.LamportTime + 1}, nil
+}
+
+func (f *fakeServer) Participants(ctx context.Context, in *proto.ClientInfo, opts ...grpc.CallOption) (*proto.ParticipantList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := &proto.ParticipantList{LamportTime: in.LamportTime + 1}
+	for id := range f.joined {
+		list.Participants = append(list.Participants, &proto.ClientInfo{ClientId: id})
+	}
+	return list, nil
+}
+
+func (f *fakeServer) ParticipantMessages(ctx context.Context, in *proto.ClientInfo, opts ...grpc.CallOption) (*proto.ServerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.published = append(f.published, in)
+	return &proto.ServerInfo{LamportTime: in.LamportTime + 10}, nil
+}
+
+func (f *fakeServer) ParticipantLeaves(ctx context.Context, in *proto.ClientInfo, opts ...grpc.CallOption) (*proto.ServerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.joined[in.ClientId] {
+		return nil, status.Error(codes.NotFound, "not a participant")
+	}
+	delete(f.joined, in.ClientId)
+	return &proto.ServerInfo{LamportTime: in.LamportTime + 1}, nil
+}
+
+func (f *fakeServer) SetNickname(ctx context.Context, in *proto.ClientInfo, opts ...grpc.CallOption) (*proto.ServerInfo, error) {
+	if f.nicknameErr != nil {
+		return nil, f.nicknameErr
+	}
+	return &proto.ServerInfo{LamportTime: in.LamportTime + 1}, nil
+}
+
+func (f *fakeServer) participants() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.joined)
+}
+
+// startGateway serves a gateway in front of a fake server
+func startGateway(t *testing.T, idle time.Duration) (*gateway, *fakeServer, *httptest.Server) {
+	fake := &fakeServer{joined: make(map[int64]bool)}
+	g := newGateway(fake, "localhost", 1000, idle)
+	mux := http.NewServeMux()
+	g.routes(mux)
+	web := httptest.NewServer(mux)
+	t.Cleanup(web.Close)
+	return g, fake, web
+}
+
+func join(t *testing.T, web *httptest.Server, nickname string) (reply, int) {
+	t.Helper()
+	return call(t, web, "/api/join", request{Nickname: nickname})
+}
+
+// call posts in to the API at path and returns the reply and the status code
+func call(t *testing.T, web *httptest.Server, path string, in request) (reply, int) {
+	t.Helper()
+
+	body, _ := json.Marshal(in)
+	response, err := http.Post(web.URL+path, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("could not call %s: %v", path, err)
+	}
+	defer response.Body.Close()
+	var out reply
+	json.NewDecoder(response.Body).Decode(&out)
+	return out, response.StatusCode
+}
+
+// openEvents opens the WebSocket of the session
+func openEvents(t *testing.T, web *httptest.Server, session string) *websocket.Conn {
+	t.Helper()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(web.URL, "http")+"/api/events?session="+session, "", web.URL)
+	if err != nil {
+		t.Fatalf("could not open the events: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// eventually waits up to a second for condition
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestJoinRejectsAnInvalidNickname(t *testing.T) {
+	_, fake, web := startGateway(t, time.Minute)
+
+	for _, nickname := range []string{"   ", strings.Repeat("x", 33)} {
+		if _, code := join(t, web, nickname); code != http.StatusBadRequest {
+			t.Errorf("joining as %q returned %d, want %d", nickname, code, http.StatusBadRequest)
+		}
+	}
+	if n := fake.participants(); n != 0 {
+		t.Errorf("%d participants joined with an invalid nickname", n)
+	}
+}
+
+func TestFailedNicknameLeavesTheChat(t *testing.T) {
+	_, fake, web := startGateway(t, time.Minute)
+	fake.nicknameErr = status.Error(codes.Unavailable, "server is going away")
+
+	if _, code := join(t, web, "alice"); code != http.StatusServiceUnavailable {
+		t.Errorf("joining returned %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if n := fake.participants(); n != 0 {
+		t.Errorf("%d participants are left in the chat without a session", n)
+	}
+}
+
+func TestIdleSessionLeaves(t *testing.T) {
+	g, fake, web := startGateway(t, 50*time.Millisecond)
+
+	out, code := join(t, web, "alice")
+	if code != http.StatusOK {
+		t.Fatalf("joining returned %d", code)
+	}
+	s := g.session(out.Session)
+	if !eventually(func() bool { return fake.participants() == 0 && g.session(out.Session) == nil }) {
+		t.Fatal("the session of a browser that never opened its events is still in the chat")
+	}
+	select {
+	case <-s.done:
+	default:
+		t.Error("the session's participant service still runs")
+	}
+}
+
+func TestSessionWithEventsStays(t *testing.T) {
+	g, fake, web := startGateway(t, 50*time.Millisecond)
+
+	out, code := join(t, web, "alice")
+	if code != http.StatusOK {
+		t.Fatalf("joining returned %d", code)
+	}
+	ws := openEvents(t, web, out.Session)
+
+	time.Sleep(200 * time.Millisecond)
+	if g.session(out.Session) == nil || fake.participants() != 1 {
+		t.Fatal("the session left although its browser opened the events")
+	}
+
+	ws.Close()
+	if !eventually(func() bool { return fake.participants() == 0 }) {
+		t.Error("the session stayed in the chat after its browser went away")
+	}
+}
+
+func TestSessionSkipsTakenIDs(t *testing.T) {
+	_, fake, web := startGateway(t, time.Minute)
+	fake.joined[1000] = true // a client started with -id 1000
+
+	out, code := join(t, web, "alice")
+	if code != http.StatusOK {
+		t.Fatalf("joining returned %d", code)
+	}
+	if out.ClientID != 1001 {
+		t.Errorf("the session got id %d, want 1001 past the client's", out.ClientID)
+	}
+}
+
+func TestBroadcastReachesTheWebSocket(t *testing.T) {
+	g, _, web := startGateway(t, time.Minute)
+	out, code := join(t, web, "alice")
+	if code != http.StatusOK {
+		t.Fatalf("joining returned %d", code)
+	}
+	ws := openEvents(t, web, out.Session)
+
+	// the server broadcasts to the session's ParticipantService
+	conn, err := grpc.Dial(g.session(out.Session).address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := proto.NewParticipantServiceClient(conn).ClientMessageReturn(context.Background(), &proto.ClientInfo{
+		ClientId:    7,
+		Nickname:    "bob",
+		Message:     "hi alice",
+		LamportTime: 50,
+	}); err != nil {
+		t.Fatalf("could not broadcast to the session: %v", err)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var e event
+	if err := websocket.JSON.Receive(ws, &e); err != nil {
+		t.Fatalf("the browser got no event: %v", err)
+	}
+	if e.Type != "message" || e.ClientID != 7 || e.Nickname != "bob" || e.Message != "hi alice" || e.Lamport != 51 {
+		t.Errorf("the browser got %+v, want the message of bob at Lamport time 51", e)
+	}
+}
+
+func TestSendIsPublished(t *testing.T) {
+	_, fake, web := startGateway(t, time.Minute)
+	out, code := join(t, web, "alice")
+	if code != http.StatusOK {
+		t.Fatalf("joining returned %d", code)
+	}
+	openEvents(t, web, out.Session)
+
+	published, code := call(t, web, "/api/messages", request{Session: out.Session, Message: "hello"})
+	if code != http.StatusOK {
+		t.Fatalf("publishing returned %d", code)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.published) != 1 || fake.published[0].ClientId != out.ClientID || fake.published[0].Message != "hello" {
+		t.Fatalf("the server got %v, want hello from participant %d", fake.published, out.ClientID)
+	}
+	// the reply of the server is merged into the session's clock
+	if sent := fake.published[0].LamportTime; published.Lamport != sent+11 {
+		t.Errorf("the session's Lamport time is %d after a reply at %d, want %d", published.Lamport, sent+10, sent+11)
+	}
+}
@@ -0,0 +1,66 @@
+// chitty-gateway lets browsers take part in a Chitty-Chat. Every browser
+// session becomes a participant: the gateway joins the server on its behalf,
+// publishes and leaves through CCService, and serves the session's own
+// ParticipantService on an ephemeral port to receive the broadcasts, which it
+// forwards as JSON over a WebSocket.
+//
+//	chitty-gateway -server localhost:5454 -listen :8000
+//
+// and open http://localhost:8000. The HTTP API used by the page:
+//
+//	POST /api/join      {"nickname": "alice"}            -> {"session": "...", "clientId": 1000, "lamport": 2}
+//	POST /api/messages  {"session": "...", "message": "hi"} -> {"lamport": 5}
+//	POST /api/leave     {"session": "..."}                -> {"lamport": 9}
+//	GET  /api/events?session=...  (WebSocket)             -> {"type": "message", "clientId": 3, "message": "hi", "lamport": 7}
+package main
+
+import (
+	"embed"
+	"flag"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var (
+	listenAddr = flag.String("listen", ":8000", "serve the web page and the API at this address")
+	serverAddr = flag.String("server", "localhost:5454", "address of the server")
+	useTLS     = flag.Bool("tls", false, "dial the server with TLS")
+	caFile     = flag.String("ca", "", "PEM file the server's certificate is checked against with -tls (default the system's)")
+	advertise  = flag.String("advertise-host", "localhost", "host name the server reaches the gateway's participants at")
+	firstID    = flag.Int64("first-id", 1000, "client id of the first browser session, counting up")
+	timeout    = flag.Duration("timeout", 10*time.Second, "how long to wait for the server")
+	idle       = flag.Duration("idle-timeout", time.Minute, "how long a session waits for its browser to open /api/events before it leaves")
+)
+
+//go:embed static
+var static embed.FS
+
+func main() {
+	flag.Parse()
+
+	creds, err := config.DialCredentials(*useTLS, *caFile)
+	if err != nil {
+		log.Fatalf("Could not load the CA certificate: %v", err)
+	}
+	conn, err := grpc.Dial(*serverAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Could not connect to %s: %v", *serverAddr, err)
+	}
+	defer conn.Close()
+
+	gateway := newGateway(proto.NewCCServiceClient(conn), *advertise, *firstID, *idle)
+
+	page, _ := fs.Sub(static, "static")
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(page)))
+	gateway.routes(mux)
+
+	slog.Info("gateway started", "listen", *listenAddr, "server", *serverAddr)
+	log.Fatal(http.ListenAndServe(*listenAddr, mux))
+}
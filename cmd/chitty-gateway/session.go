@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// event is what the browser receives on the WebSocket
+type event struct {
+	Type     string `json:"type"` // message, dm, announcement, join, leave or error
+	ClientID int64  `json:"clientId"`
+	Nickname string `json:"nickname,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Lamport  int64  `json:"lamport"` // the session's Lamport time after receiving the broadcast
+}
+
+// session is the participant of one browser. It keeps its own Lamport clock
+// and serves the ParticipantService the server sends the broadcasts to.
+type session struct {
+	proto.UnimplementedParticipantServiceServer
+	id          int64
+	address     string
+	port        int
+	server      proto.CCServiceClient
+	grpcServer  *grpc.Server
+	idle        *time.Timer   // drops the session unless the browser opens /api/events in time
+	events      chan event    // buffered, broadcasts are dropped while the browser lags behind
+	done        chan struct{} // closed when the session has stopped
+	stopOnce    sync.Once
+	clockMu     sync.Mutex // guards lamportTime
+	lamportTime int64
+}
+
+// startSession serves a new participant's ParticipantService on an ephemeral port
+func startSession(server proto.CCServiceClient, id int64, host string) (*session, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	s := &session{
+		id:          id,
+		address:     net.JoinHostPort(host, strconv.Itoa(port)),
+		port:        port,
+		server:      server,
+		grpcServer:  grpc.NewServer(),
+		events:      make(chan event, 64),
+		done:        make(chan struct{}),
+		lamportTime: 1,
+	}
+	proto.RegisterParticipantServiceServer(s.grpcServer, s)
+	go s.grpcServer.Serve(listener)
+	return s, nil
+}
+
+func (s *session) join(ctx context.Context, nickname string) error {
+	_, err := s.server.ParticipantJoins(ctx, &proto.ClientInfo{
+		ClientId:    s.id,
+		LamportTime: s.tick(),
+		PortNumber:  int64(s.port),
+		Address:     s.address,
+	})
+	if err != nil || nickname == "" {
+		return err
+	}
+	_, err = s.server.SetNickname(ctx, &proto.ClientInfo{
+		ClientId:    s.id,
+		LamportTime: s.tick(),
+		Nickname:    nickname,
+	})
+	if err != nil {
+		// the browser gets no session, so take the participant out again;
+		// ctx may be what ran out
+		leaveCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		if _, leaveErr := s.server.ParticipantLeaves(leaveCtx, &proto.ClientInfo{
+			ClientId:    s.id,
+			LamportTime: s.tick(),
+		}); leaveErr != nil {
+			slog.Warn("could not leave after a failed nickname", "client", s.id, "error", leaveErr)
+		}
+	}
+	return err
+}
+
+func (s *session) publish(ctx context.Context, message string) error {
+	reply, err := s.server.ParticipantMessages(ctx, &proto.ClientInfo{
+		ClientId:    s.id,
+		LamportTime: s.tick(),
+		Message:     message,
+	})
+	if err != nil {
+		return err
+	}
+	s.receive(reply.LamportTime)
+	return nil
+}
+
+// leave takes the participant out of the chat and stops its service
+func (s *session) leave(ctx context.Context) error {
+	defer s.stop()
+	_, err := s.server.ParticipantLeaves(ctx, &proto.ClientInfo{
+		ClientId:    s.id,
+		LamportTime: s.tick(),
+	})
+	slog.Info("browser left", "client", s.id)
+	return err
+}
+
+func (s *session) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.grpcServer.Stop()
+	})
+}
+
+// forward hands an event to the WebSocket without blocking the server's broadcast
+func (s *session) forward(e event) {
+	select {
+	case s.events <- e:
+	default:
+		slog.Warn("browser lags behind, dropping event", "client", s.id, "type", e.Type)
+	}
+}
+
+// when the server broadcasts that a participant joined
+func (s *session) ClientJoinReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.receive(in.LamportTime)
+	s.forward(event{Type: "join", ClientID: in.ClientId, Lamport: lamportTime})
+	return &proto.ServerInfo{LamportTime: lamportTime}, nil
+}
+
+// when the server broadcasts a published message
+func (s *session) ClientMessageReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.receive(in.LamportTime)
+	kind := "message"
+	switch {
+	case in.Announcement:
+		kind = "announcement"
+	case in.RecipientId != 0:
+		kind = "dm"
+	}
+	s.forward(event{Type: kind, ClientID: in.ClientId, Nickname: in.Nickname, Message: in.Message, Lamport: lamportTime})
+	return &proto.ServerInfo{LamportTime: lamportTime}, nil
+}
+
+// when the server broadcasts that a participant left or was kicked
+func (s *session) ClientLeaveReturn(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.receive(in.LamportTime)
+	s.forward(event{Type: "leave", ClientID: in.ClientId, Message: in.Message, Lamport: lamportTime})
+	if in.ClientId == s.id {
+		// kicked by an operator; stop outside the handler, stopping may wait for it
+		go s.stop()
+	}
+	return &proto.ServerInfo{LamportTime: lamportTime}, nil
+}
+
+// tick advances the Lamport clock for a send and returns the new time
+func (s *session) tick() int64 {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	s.lamportTime++
+	return s.lamportTime
+}
+
+// clock returns the current Lamport time
+func (s *session) clock() int64 {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	return s.lamportTime
+}
+
+// receive merges a received timestamp into the Lamport clock and returns the new time
+func (s *session) receive(lamportTime int64) int64 {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	if s.lamportTime < lamportTime {
+		s.lamportTime = lamportTime
+	}
+	s.lamportTime++
+	return s.lamportTime
+}
@@ -0,0 +1,45 @@
+// chitty-merge merges the event logs of the server and the participants into
+// one trace that can be uploaded to ShiViz (https://bestchai.bitbucket.io/shiviz/).
+//
+//	go run ./cmd/chitty-merge -o trace.log server.log client-1.log client-2.log
+package main
+
+import (
+	"flag"
+	"github.com/Tien197/Chitty-Chat/eventlog"
+	"log"
+	"os"
+)
+
+var output = flag.String("o", "", "file to write the merged trace to (default stdout)")
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("Usage: chitty-merge [-o trace.log] eventlog...")
+	}
+
+	var events []eventlog.Event
+	for _, path := range flag.Args() {
+		fileEvents, err := eventlog.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Could not read event log: %v", err)
+		}
+		events = append(events, fileEvents...)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Could not create %s: %v", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := eventlog.Merge(out, events); err != nil {
+		log.Fatalf("Could not write trace: %v", err)
+	}
+	log.Printf("Merged %d events from %d logs", len(events), flag.NArg())
+}
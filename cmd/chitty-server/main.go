@@ -0,0 +1,84 @@
+// chitty-server runs the Chitty-Chat server until it gets SIGINT or SIGTERM.
+//
+//	chitty-server -port 5454
+//	chitty-server -config config/server.example.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/server"
+	"github.com/Tien197/Chitty-Chat/tracing"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// The settings of the server. The flags are bound to its fields and config.Load
+// fills in the rest from the config file and the CHITTY_* environment variables.
+var cfg config.Server
+
+var configPath = flag.String("config", os.Getenv("CHITTY_CONFIG"), "read settings from this YAML file")
+
+func init() {
+	// Used to get the user-defined port for the server from the command line
+	flag.IntVar(&cfg.Port, "port", 0, "server port number")
+	flag.StringVar(&cfg.EventLog, "eventlog", "", "write a vector clock event log (GoVector/ShiViz format) to this file")
+	flag.StringVar(&cfg.DataDir, "data-dir", "", "keep the history of every room in this directory across restarts")
+	flag.StringVar(&cfg.Clock, "clock", config.VectorClocks, "timestamps sent with every message: vector (Lamport time and vector clock) or lamport")
+	flag.StringVar(&cfg.Log.Format, "log-format", "text", "log output format: text or json")
+	flag.StringVar(&cfg.Log.Level, "log-level", "info", "minimum log level: debug, info, warn or error")
+	flag.StringVar(&cfg.Metrics, "metrics", "", "serve Prometheus metrics at this address, e.g. :9100")
+	flag.StringVar(&cfg.Trace.Exporter, "trace-exporter", "none", "export OpenTelemetry spans: none, stdout or otlp")
+	flag.StringVar(&cfg.Trace.Endpoint, "trace-endpoint", "localhost:4317", "address of the OTLP collector for -trace-exporter otlp")
+	flag.StringVar(&cfg.AdminToken, "admin-token", "", "enable the AdminService for callers presenting this token")
+	flag.StringVar(&cfg.HTTP, "http", "", "serve the REST/JSON API of CCService at this address, e.g. :8080")
+	flag.IntVar(&cfg.Webhooks.Retries, "webhook-retries", 3, "retry a failed webhook delivery this many times")
+	flag.StringVar(&cfg.Webhooks.DeadLetter, "webhook-dead-letter", "", "append webhook events that could not be delivered to this file")
+	flag.StringVar(&cfg.TLS.Cert, "tls-cert", "", "serve with TLS, presenting this PEM certificate")
+	flag.StringVar(&cfg.TLS.Key, "tls-key", "", "PEM file of the private key of -tls-cert")
+	flag.BoolVar(&cfg.Reflection, "reflection", false, "enable gRPC server reflection, e.g. for grpcurl")
+	flag.DurationVar(&cfg.Timeouts.Delivery, "delivery-timeout", 5*time.Second, "give up delivering a broadcast to a participant after this long")
+}
+
+func main() {
+	flag.Parse()
+	if err := config.Load(flag.CommandLine, *configPath, &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	level, err := logging.Setup(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("Could not set up logging: %v", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "chitty-chat-server", cfg.Trace.Exporter, cfg.Trace.Endpoint)
+	if err != nil {
+		log.Fatalf("Could not set up tracing: %v", err)
+	}
+
+	s, err := server.New(server.Options{Server: cfg, LogLevel: level})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Keep the server running until it is manually quit
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	if err := s.Stop(); err != nil {
+		slog.Error("could not stop the server cleanly", "error", err)
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("could not flush spans", "error", err)
+	}
+}
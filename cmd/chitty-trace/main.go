@@ -0,0 +1,173 @@
+// chitty-trace renders the event logs of the server and the participants as a
+// sequence diagram of the RPC calls annotated with their Lamport times, in
+// Mermaid (default) or PlantUML syntax.
+//
+//	go run ./cmd/chitty-trace -o trace.mmd server.log client-1.log client-2.log
+//	go run ./cmd/chitty-trace -format plantuml -kinds join,publish server.log client-1.log
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/eventlog"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	format = flag.String("format", "mermaid", "diagram syntax: mermaid or plantuml")
+	kinds  = flag.String("kinds", "", "comma separated event kinds to include, e.g. join,publish (default all)")
+	output = flag.String("o", "", "file to write the diagram to (default stdout)")
+)
+
+// syntax is the part of the diagram that differs between Mermaid and PlantUML
+type syntax struct {
+	header      string
+	footer      string
+	participant string // alias, name
+	arrow       string // from, to, label
+	lost        string // from, to, label; a send that was never received
+	note        string // alias, text
+}
+
+var syntaxes = map[string]syntax{
+	"mermaid": {
+		header:      "sequenceDiagram\n",
+		participant: "    participant %s as %s\n",
+		arrow:       "    %s->>%s: %s\n",
+		lost:        "    %s-x%s: %s\n",
+		note:        "    Note over %s: %s\n",
+	},
+	"plantuml": {
+		header:      "@startuml\n",
+		footer:      "@enduml\n",
+		participant: "participant \"%[2]s\" as %[1]s\n",
+		arrow:       "%s -> %s : %s\n",
+		lost:        "%s ->x %s : %s\n",
+		note:        "note over %s : %s\n",
+	},
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("Usage: chitty-trace [-format mermaid|plantuml] [-kinds join,publish] [-o file] eventlog...")
+	}
+	diagram, ok := syntaxes[*format]
+	if !ok {
+		log.Fatalf("Unknown format %q, use mermaid or plantuml", *format)
+	}
+
+	var events []eventlog.Event
+	for _, path := range flag.Args() {
+		fileEvents, err := eventlog.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Could not read event log: %v", err)
+		}
+		events = append(events, fileEvents...)
+	}
+	eventlog.Sort(events)
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Could not create %s: %v", *output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	w := bufio.NewWriter(out)
+	render(w, diagram, events, included(*kinds))
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Could not write diagram: %v", err)
+	}
+}
+
+// included returns a filter for the comma separated list of event kinds
+func included(list string) func(kind string) bool {
+	if list == "" {
+		return func(string) bool { return true }
+	}
+	wanted := make(map[string]bool)
+	for _, kind := range strings.Split(list, ",") {
+		wanted[strings.TrimSpace(kind)] = true
+	}
+	return func(kind string) bool {
+		// a broadcast or reply is shown together with the kind it belongs to
+		base, _, _ := strings.Cut(kind, "-")
+		return wanted[kind] || wanted[base]
+	}
+}
+
+func render(w io.Writer, diagram syntax, events []eventlog.Event, include func(kind string) bool) {
+	// the server first, then the participants in name order
+	hosts := make(map[string]bool)
+	for _, event := range events {
+		hosts[event.Host] = true
+		if event.Peer != "" {
+			hosts[event.Peer] = true
+		}
+	}
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "server") != (names[j] == "server") {
+			return names[i] == "server"
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprint(w, diagram.header)
+	alias := make(map[string]string)
+	for i, name := range names {
+		alias[name] = fmt.Sprintf("p%d", i)
+		fmt.Fprintf(w, diagram.participant, alias[name], name)
+	}
+
+	// every message is drawn where it was sent, or where it was received if the send is not logged
+	messages := eventlog.Match(events)
+	at := make(map[*eventlog.Event]eventlog.Message)
+	for _, message := range messages {
+		if message.Send != nil {
+			at[message.Send] = message
+		} else {
+			at[message.Receive] = message
+		}
+	}
+
+	for i := range events {
+		event := &events[i]
+		if !include(event.Kind) {
+			continue
+		}
+		if event.Dir == "" {
+			fmt.Fprintf(w, diagram.note, alias[event.Host], fmt.Sprintf("%s (L=%d)", event.Kind, event.Lamport))
+			continue
+		}
+
+		message, ok := at[event]
+		if !ok {
+			continue
+		}
+		switch {
+		case message.Send != nil && message.Receive != nil:
+			fmt.Fprintf(w, diagram.arrow, alias[message.Send.Host], alias[message.Receive.Host],
+				fmt.Sprintf("%s (L=%d → %d)", event.Kind, message.Send.Lamport, message.Receive.Lamport))
+		case message.Send != nil:
+			fmt.Fprintf(w, diagram.lost, alias[message.Send.Host], alias[message.Send.Peer],
+				fmt.Sprintf("%s (L=%d)", event.Kind, message.Send.Lamport))
+		default:
+			fmt.Fprintf(w, diagram.arrow, alias[message.Receive.Peer], alias[message.Receive.Host],
+				fmt.Sprintf("%s (L=? → %d)", event.Kind, message.Receive.Lamport))
+		}
+	}
+	fmt.Fprint(w, diagram.footer)
+}
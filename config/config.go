@@ -0,0 +1,371 @@
+// Package config loads the settings of the server and the client from a YAML
+// file and CHITTY_* environment variables on top of the command line flags.
+//
+// A setting is taken from, in increasing order of precedence: the flag's
+// default, the config file, the environment, and a flag given on the command
+// line. The environment variable of a key is CHITTY_ followed by its path in
+// upper case, e.g. server.host in the client config is CHITTY_SERVER_HOST.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// EnvPrefix starts the name of every environment variable read by Load.
+const EnvPrefix = "CHITTY_"
+
+// Log configures the structured logger.
+type Log struct {
+	Format string `yaml:"format"` // text or json
+	Level  string `yaml:"level"`  // debug, info, warn or error
+}
+
+// Trace configures the OpenTelemetry exporter.
+type Trace struct {
+	Exporter string `yaml:"exporter"` // none, stdout or otlp
+	Endpoint string `yaml:"endpoint"` // OTLP collector address
+}
+
+// TLS is the certificate a server presents to its callers.
+type TLS struct {
+	Cert string `yaml:"cert"` // PEM file; served without TLS when empty
+	Key  string `yaml:"key"`  // PEM file of the private key of cert
+}
+
+// Webhook is an HTTP endpoint that receives chat events, see package webhook.
+type Webhook struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"` // signs the body when set
+	Events []string `yaml:"events"` // join, leave and message; every event when empty
+}
+
+// Server is the configuration of the server.
+type Server struct {
+	Port       int    `yaml:"port"`
+	AdminToken string `yaml:"admin_token"`
+	Reflection bool   `yaml:"reflection"`
+	TLS        TLS    `yaml:"tls"`
+	HTTP       string `yaml:"http"` // address of the REST/JSON gateway
+	EventLog   string `yaml:"eventlog"`
+	DataDir    string `yaml:"data_dir"` // keeps the room histories across restarts; nothing is kept when empty
+	Clock      string `yaml:"clock"`    // vector or lamport, see VectorClocks
+	Metrics    string `yaml:"metrics"`
+	Log        Log    `yaml:"log"`
+	Trace      Trace  `yaml:"trace"`
+	Webhooks   struct {
+		Hooks      []Webhook `yaml:"hooks"`       // only set in the file
+		Retries    int       `yaml:"retries"`     // after the first attempt
+		DeadLetter string    `yaml:"dead_letter"` // file the undeliverable events are appended to
+	} `yaml:"webhooks"`
+	Timeouts struct {
+		Delivery time.Duration `yaml:"delivery"` // per participant when broadcasting
+	} `yaml:"timeouts"`
+}
+
+// Client is the configuration of a participant.
+type Client struct {
+	ID        int    `yaml:"id"`
+	Port      int    `yaml:"port"`      // port of the participant's own ParticipantService
+	Advertise string `yaml:"advertise"` // host:port the server and peers call back; localhost:port when empty
+	Server    struct {
+		Address string `yaml:"address"` // host:port, takes precedence over host and port
+		Host    string `yaml:"host"`
+		Port    int    `yaml:"port"`
+		TLS     bool   `yaml:"tls"` // dial the server with TLS
+		CA      string `yaml:"ca"`  // PEM file the server's certificate is checked against; the system's when empty
+	} `yaml:"server"`
+	Script   string `yaml:"script"`        // actions to run instead of reading the terminal
+	Message  string `yaml:"message"`       // publish only this message
+	TUI      bool   `yaml:"tui"`           // full-screen terminal UI
+	Mutex    bool   `yaml:"mutex"`         // request the floor before publishing; the server rejects others while it is held
+	Receipts bool   `yaml:"read_receipts"` // tell the publishers which of their messages were shown
+	Election string `yaml:"election"`
+	Nickname string `yaml:"nickname"` // set after joining, like /nick
+	Room     string `yaml:"room"`     // moved to after joining, like /join; the lobby when empty
+	EventLog string `yaml:"eventlog"`
+	Clock    string `yaml:"clock"` // vector or lamport, see VectorClocks
+	Metrics  string `yaml:"metrics"`
+	Log      Log    `yaml:"log"`
+	Trace    Trace  `yaml:"trace"`
+	Timeouts struct {
+		Connect time.Duration `yaml:"connect"` // joining the server
+		RPC     time.Duration `yaml:"rpc"`     // every other call to the server
+	} `yaml:"timeouts"`
+}
+
+// Validate reports every setting that is out of range.
+func (c *Server) Validate() error {
+	return errors.Join(
+		checkPort("port", c.Port),
+		checkTLS(c.TLS),
+		checkClock(c.Clock),
+		checkLog(c.Log),
+		checkTrace(c.Trace),
+		checkWebhooks(c.Webhooks.Hooks),
+		checkRetries("webhooks.retries", c.Webhooks.Retries),
+		checkPositive("timeouts.delivery", c.Timeouts.Delivery),
+	)
+}
+
+// Validate reports every setting that is out of range.
+func (c *Client) Validate() error {
+	var election error
+	if c.Election != "" && c.Election != "bully" && c.Election != "ring" {
+		election = fmt.Errorf("election: %q is not bully or ring", c.Election)
+	}
+	var tui error
+	if c.TUI && (c.Script != "" || c.Message != "") {
+		tui = errors.New("tui: cannot be combined with script or message")
+	}
+	// the server's rules for /nick and /join, checked before joining
+	var nickname error
+	if c.Nickname != "" && (strings.TrimSpace(c.Nickname) == "" || utf8.RuneCountInString(c.Nickname) > 32) {
+		nickname = fmt.Errorf("nickname: %q is not 1 to 32 characters", c.Nickname)
+	}
+	var room error
+	if strings.ContainsAny(c.Room, " \t") || utf8.RuneCountInString(c.Room) > 32 {
+		room = fmt.Errorf("room: %q is not a single word of at most 32 characters", c.Room)
+	}
+	var host error
+	if c.Server.Host == "" && c.Server.Address == "" {
+		host = errors.New("server.host: must not be empty")
+	}
+	var ca error
+	if c.Server.CA != "" && !c.Server.TLS {
+		ca = errors.New("server.ca: needs server.tls")
+	}
+	return errors.Join(
+		checkPort("port", c.Port),
+		checkAddress("advertise", c.Advertise),
+		checkAddress("server.address", c.Server.Address),
+		host,
+		checkPort("server.port", c.Server.Port),
+		ca,
+		checkFile("server.ca", c.Server.CA),
+		election,
+		nickname,
+		room,
+		tui,
+		checkClock(c.Clock),
+		checkLog(c.Log),
+		checkTrace(c.Trace),
+		checkPositive("timeouts.connect", c.Timeouts.Connect),
+		checkPositive("timeouts.rpc", c.Timeouts.RPC),
+	)
+}
+
+// ServerAddress returns the host:port of the server.
+func (c *Client) ServerAddress() string {
+	if c.Server.Address != "" {
+		return c.Server.Address
+	}
+	return net.JoinHostPort(c.Server.Host, strconv.Itoa(c.Server.Port))
+}
+
+// checkAddress accepts an empty address or host:port
+func checkAddress(key, address string) error {
+	if address == "" {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	if host == "" {
+		return fmt.Errorf("%s: %q has no host", key, address)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("%s: %q has no valid port", key, address)
+	}
+	return nil
+}
+
+func checkPort(key string, port int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("%s: %d is not a port number between 0 and 65535", key, port)
+	}
+	return nil
+}
+
+func checkTLS(t TLS) error {
+	if (t.Cert == "") != (t.Key == "") {
+		return errors.New("tls: cert and key go together")
+	}
+	return errors.Join(checkFile("tls.cert", t.Cert), checkFile("tls.key", t.Key))
+}
+
+// checkFile accepts an empty path or one of a readable file
+func checkFile(key, path string) error {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return file.Close()
+}
+
+// Clock modes. With VectorClocks every message carries the sender's vector
+// clock next to its Lamport time, so that the event logs show which events
+// are concurrent; with LamportOnly it carries the Lamport time alone and the
+// vector clock in the event log only counts the process's own events. The
+// empty mode is VectorClocks.
+const (
+	VectorClocks = "vector"
+	LamportOnly  = "lamport"
+)
+
+func checkClock(mode string) error {
+	if mode != "" && mode != VectorClocks && mode != LamportOnly {
+		return fmt.Errorf("clock: %q is not vector or lamport", mode)
+	}
+	return nil
+}
+
+func checkLog(log Log) error {
+	var errs []error
+	if log.Format != "text" && log.Format != "json" {
+		errs = append(errs, fmt.Errorf("log.format: %q is not text or json", log.Format))
+	}
+	switch strings.ToLower(log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level: %q is not debug, info, warn or error", log.Level))
+	}
+	return errors.Join(errs...)
+}
+
+func checkTrace(trace Trace) error {
+	switch trace.Exporter {
+	case "", "none", "stdout", "otlp":
+		return nil
+	}
+	return fmt.Errorf("trace.exporter: %q is not none, stdout or otlp", trace.Exporter)
+}
+
+func checkWebhooks(hooks []Webhook) error {
+	var errs []error
+	for i, hook := range hooks {
+		key := fmt.Sprintf("webhooks.hooks[%d]", i)
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.url: %q is not an http or https URL", key, hook.URL))
+		}
+		for _, event := range hook.Events {
+			if event != "join" && event != "leave" && event != "message" {
+				errs = append(errs, fmt.Errorf("%s.events: %q is not join, leave or message", key, event))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkRetries(key string, retries int) error {
+	if retries < 0 {
+		return fmt.Errorf("%s: %d must not be negative", key, retries)
+	}
+	return nil
+}
+
+func checkPositive(key string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s: %s must be positive", key, d)
+	}
+	return nil
+}
+
+// Load fills cfg, whose fields the flags of fs are bound to, from the YAML
+// file at path (if any) and the environment, keeps the flags that were given
+// on the command line, and validates the result. fs must already be parsed.
+func Load(fs *flag.FlagSet, path string, cfg interface{ Validate() error }) error {
+	// remember the flags given explicitly, the file and environment must not override them
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = f.Value.String() })
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	if err := fromEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix); err != nil {
+		return err
+	}
+
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		if path != "" {
+			return fmt.Errorf("invalid configuration (%s): %w", path, err)
+		}
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fromEnv sets the fields of v from the environment variables named after their yaml keys
+func fromEnv(v reflect.Value, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		name := prefix + strings.ToUpper(key)
+
+		if field.Kind() == reflect.Struct {
+			if err := fromEnv(field, name+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		var err error
+		switch {
+		case field.Type() == durationType:
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.Int:
+			var n int64
+			n, err = strconv.ParseInt(value, 10, 0)
+			field.SetInt(n)
+		case field.Kind() == reflect.Bool:
+			var b bool
+			b, err = strconv.ParseBool(value)
+			field.SetBool(b)
+		case field.Kind() == reflect.String:
+			field.SetString(value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %q: %v", name, value, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"crypto/tls"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Credentials returns the credentials a server is served with: its certificate,
+// or insecure ones without.
+func (t TLS) Credentials() (credentials.TransportCredentials, error) {
+	if t.Cert == "" {
+		return insecure.NewCredentials(), nil
+	}
+	return credentials.NewServerTLSFromFile(t.Cert, t.Key)
+}
+
+// DialCredentials returns the credentials a server is dialed with. With useTLS
+// its certificate is checked against the PEM file ca, or the system's
+// certificates when ca is empty; without, the connection is insecure.
+func DialCredentials(useTLS bool, ca string) (credentials.TransportCredentials, error) {
+	if !useTLS {
+		return insecure.NewCredentials(), nil
+	}
+	if ca == "" {
+		return credentials.NewTLS(&tls.Config{}), nil
+	}
+	return credentials.NewClientTLSFromFile(ca, "")
+}
+
+// ServerCredentials returns the credentials the client dials the server with.
+func (c *Client) ServerCredentials() (credentials.TransportCredentials, error) {
+	return DialCredentials(c.Server.TLS, c.Server.CA)
+}
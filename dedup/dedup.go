@@ -0,0 +1,67 @@
+// Package dedup gives published messages unique ids and remembers the ids
+// seen recently, so that a publish retried after a timeout is broadcast and
+// shown once even if the first attempt got through.
+package dedup
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// NewID returns a random message id of 32 hex digits.
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("dedup: could not read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Window remembers the last ids it was given, each with an optional value. It
+// is safe for concurrent use.
+type Window struct {
+	mu    sync.Mutex
+	seen  map[string]any
+	order []string // ring of the ids in seen, oldest at next
+	next  int
+}
+
+// NewWindow returns a window that remembers size ids, at least one.
+func NewWindow(size int) *Window {
+	if size < 1 {
+		size = 1
+	}
+	return &Window{
+		seen:  make(map[string]any, size),
+		order: make([]string, 0, size),
+	}
+}
+
+// Seen records id and reports whether it is already in the window. Once the
+// window is full, recording an id forgets the oldest one.
+func (w *Window) Seen(id string) bool {
+	_, seen := w.LoadOrStore(id, nil)
+	return seen
+}
+
+// LoadOrStore returns the value recorded with id and true if id is in the
+// window. Otherwise it records id with value, like Seen, and returns value and
+// false.
+func (w *Window) LoadOrStore(id string, value any) (any, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if actual, ok := w.seen[id]; ok {
+		return actual, true
+	}
+	if len(w.order) < cap(w.order) {
+		w.order = append(w.order, id)
+	} else {
+		delete(w.seen, w.order[w.next])
+		w.order[w.next] = id
+		w.next = (w.next + 1) % len(w.order)
+	}
+	w.seen[id] = value
+	return value, false
+}
@@ -0,0 +1,42 @@
+package dedup
+
+import "testing"
+
+func TestWindowForgetsTheOldestID(t *testing.T) {
+	w := NewWindow(2)
+	for _, id := range []string{"a", "b"} {
+		if w.Seen(id) {
+			t.Fatalf("%s seen before it was recorded", id)
+		}
+	}
+	if !w.Seen("a") || !w.Seen("b") {
+		t.Fatal("an id in the window was not seen")
+	}
+
+	w.Seen("c") // forgets a
+	if w.Seen("a") {
+		t.Error("a is still seen after two newer ids")
+	}
+	if !w.Seen("c") {
+		t.Error("c is not seen")
+	}
+}
+
+func TestLoadOrStoreKeepsTheFirstValue(t *testing.T) {
+	w := NewWindow(2)
+	if value, loaded := w.LoadOrStore("a", 1); loaded || value != 1 {
+		t.Fatalf("LoadOrStore of a new id returned %v, %v", value, loaded)
+	}
+	if value, loaded := w.LoadOrStore("a", 2); !loaded || value != 1 {
+		t.Errorf("LoadOrStore of a recorded id returned %v, %v, want 1, true", value, loaded)
+	}
+	if !w.Seen("a") {
+		t.Error("an id recorded with a value is not seen")
+	}
+}
+
+func TestNewIDIsUnique(t *testing.T) {
+	if a, b := NewID(), NewID(); a == b || len(a) != 32 {
+		t.Errorf("NewID returned %q and %q", a, b)
+	}
+}
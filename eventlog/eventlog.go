@@ -0,0 +1,315 @@
+// Package eventlog keeps a vector clock next to the Lamport clock and writes
+// every event to a log in the GoVector format, so that the logs of the server
+// and the participants can be merged and shown in ShiViz.
+//
+// Each event takes two lines:
+//
+//	client-1 {"client-1":2,"server":1}
+//	join lamport=2 to=server: Client 1 requests to join server
+//
+// The first line is the process and its vector clock, the second the event
+// description. The description starts with the event kind, the Lamport time
+// and the other side of a send (to=) or receive (from=), which lets tools such
+// as chitty-trace pair messages without guessing from the text.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ShiVizRegex parses the event log; it has to be given to ShiViz together with the log.
+const ShiVizRegex = `(?<host>\S*) (?<clock>{.*})\n(?<event>.*)`
+
+// Event is one entry of the event log.
+type Event struct {
+	Host    string
+	Clock   map[string]int64
+	Kind    string
+	Lamport int64
+	Dir     string // "to" for a send, "from" for a receive and "" for a local event
+	Peer    string
+	Text    string
+}
+
+// Logger tracks the vector clock of one process and logs its events.
+type Logger struct {
+	mu    sync.Mutex
+	host  string
+	clock map[string]int64
+	out   io.Writer // nil when the events are not written anywhere
+	file  *os.File  // opened by Open, closed by Close
+}
+
+// New returns a logger for host writing to out. The vector clock is kept even when out is nil.
+func New(host string, out io.Writer) *Logger {
+	return &Logger{
+		host:  host,
+		clock: map[string]int64{},
+		out:   out,
+	}
+}
+
+// Open returns a logger for host that writes to the file at path, or only keeps
+// the vector clock when path is empty.
+func Open(host, path string) (*Logger, error) {
+	if path == "" {
+		return New(host, nil), nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := New(host, file)
+	l.file = file
+	return l, nil
+}
+
+// Close closes the file opened by Open. Events logged afterwards only advance
+// the vector clock.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file, l.out = nil, nil
+	return err
+}
+
+// Host returns the process name used in the log.
+func (l *Logger) Host() string {
+	return l.host
+}
+
+// LocalEvent records an event that is neither a send nor a receive.
+func (l *Logger) LocalEvent(kind string, lamport int, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clock[l.host]++
+	l.write(Event{Kind: kind, Lamport: int64(lamport), Text: text})
+}
+
+// Send records sending a message to peer and returns the vector clock to attach to it.
+func (l *Logger) Send(kind, peer string, lamport int, text string) map[string]int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clock[l.host]++
+	l.write(Event{Kind: kind, Lamport: int64(lamport), Dir: "to", Peer: peer, Text: text})
+	return l.copyClock()
+}
+
+// Receive merges the vector clock of a message from peer and records the receive.
+func (l *Logger) Receive(kind, peer string, lamport int, clock map[string]int64, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for host, time := range clock {
+		if l.clock[host] < time {
+			l.clock[host] = time
+		}
+	}
+	l.clock[l.host]++
+	l.write(Event{Kind: kind, Lamport: int64(lamport), Dir: "from", Peer: peer, Text: text})
+}
+
+// Clock returns a copy of the current vector clock.
+func (l *Logger) Clock() map[string]int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.copyClock()
+}
+
+func (l *Logger) copyClock() map[string]int64 {
+	clock := make(map[string]int64, len(l.clock))
+	for host, time := range l.clock {
+		clock[host] = time
+	}
+	return clock
+}
+
+// write must be called with l.mu held, after the clock has been advanced
+func (l *Logger) write(event Event) {
+	if l.out == nil {
+		return
+	}
+	event.Host = l.host
+	event.Clock = l.clock
+	fmt.Fprint(l.out, event.String())
+}
+
+// String formats the event as its two log lines.
+func (e Event) String() string {
+	clock, _ := json.Marshal(e.Clock)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n%s lamport=%d", e.Host, clock, e.Kind, e.Lamport)
+	if e.Dir != "" {
+		fmt.Fprintf(&b, " %s=%s", e.Dir, e.Peer)
+	}
+	fmt.Fprintf(&b, ": %s\n", e.Text)
+	return b.String()
+}
+
+var (
+	headerLine      = regexp.MustCompile(`^(\S+) ({.*})$`)
+	descriptionLine = regexp.MustCompile(`^(\S+) lamport=(\d+)(?: (to|from)=(\S+))?: (.*)$`)
+)
+
+// Read parses an event log written by a Logger or by Merge. ShiViz header lines are skipped.
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		header := headerLine.FindStringSubmatch(scanner.Text())
+		if header == nil {
+			continue
+		}
+
+		event := Event{Host: header[1]}
+		if err := json.Unmarshal([]byte(header[2]), &event.Clock); err != nil {
+			return nil, fmt.Errorf("line %d: bad vector clock: %v", line, err)
+		}
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("line %d: event without description", line)
+		}
+		line++
+		description := descriptionLine.FindStringSubmatch(scanner.Text())
+		if description == nil {
+			return nil, fmt.Errorf("line %d: bad event description %q", line, scanner.Text())
+		}
+		event.Kind = description[1]
+		event.Lamport, _ = strconv.ParseInt(description[2], 10, 64)
+		event.Dir = description[3]
+		event.Peer = description[4]
+		event.Text = description[5]
+
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// ReadFile parses the event log at path.
+func ReadFile(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return events, nil
+}
+
+// Sort orders events so that every event comes after the events that happened
+// before it. Concurrent events are ordered by Lamport time and then by host.
+func Sort(events []Event) {
+	sum := func(clock map[string]int64) int64 {
+		var total int64
+		for _, time := range clock {
+			total += time
+		}
+		return total
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		// if a happened before b, a's clock sum is strictly smaller
+		si, sj := sum(events[i].Clock), sum(events[j].Clock)
+		if si != sj {
+			return si < sj
+		}
+		if events[i].Lamport != events[j].Lamport {
+			return events[i].Lamport < events[j].Lamport
+		}
+		return events[i].Host < events[j].Host
+	})
+}
+
+// Merge writes the events of several processes as one trace that can be uploaded to ShiViz.
+func Merge(w io.Writer, events []Event) error {
+	merged := append([]Event(nil), events...)
+	Sort(merged)
+
+	// ShiViz reads the parser regex from the first line and the execution delimiter from the second
+	if _, err := fmt.Fprintf(w, "%s\n\n", ShiVizRegex); err != nil {
+		return err
+	}
+	for _, event := range merged {
+		if _, err := fmt.Fprint(w, event.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Message is a send paired with its receive. Either side is nil when it is not in the logs.
+type Message struct {
+	Send    *Event
+	Receive *Event
+}
+
+// Match pairs every send with the receive of the same message. A receive from
+// peer P matches the send from P whose own clock entry it carries; if P's clock
+// has moved on through other messages, or P sent no vector clock, the oldest
+// unmatched send is used. Events must be sorted with Sort first.
+func Match(events []Event) []Message {
+	var messages []Message
+	sent := make(map[*Event]int) // send -> index in messages
+	for i := range events {
+		event := &events[i]
+		if event.Dir == "to" {
+			sent[event] = len(messages)
+			messages = append(messages, Message{Send: event})
+		}
+	}
+
+	for i := range events {
+		receive := &events[i]
+		if receive.Dir != "from" {
+			continue
+		}
+
+		var match *Event
+		for j := range events {
+			send := &events[j]
+			if send.Dir != "to" || send.Host != receive.Peer || send.Peer != receive.Host ||
+				send.Kind != receive.Kind || messages[sent[send]].Receive != nil {
+				continue
+			}
+			if seen, ok := receive.Clock[send.Host]; ok && send.Clock[send.Host] > seen {
+				continue
+			}
+			if send.Clock[send.Host] == receive.Clock[send.Host] {
+				match = send
+				break
+			}
+			if match == nil {
+				match = send
+			}
+		}
+
+		if match == nil {
+			messages = append(messages, Message{Receive: receive})
+		} else {
+			messages[sent[match]].Receive = receive
+		}
+	}
+	return messages
+}
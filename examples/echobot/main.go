@@ -0,0 +1,71 @@
+// echobot is a participant written with the chitty package: it repeats every
+// message published in its room and welcomes the participants that join. It
+// does not repeat direct messages, which would make them public, nor echoes,
+// so that two bots do not echo each other forever.
+//
+//	go run ./examples/echobot -server localhost:5454 -id 99
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/chitty"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+)
+
+var (
+	server   = flag.String("server", "localhost:5454", "address of the server")
+	id       = flag.Int64("id", 99, "client id of the bot")
+	nickname = flag.String("nickname", "echo", "nickname of the bot")
+)
+
+const echoPrefix = "echo: "
+
+func main() {
+	flag.Parse()
+
+	bot := chitty.New(chitty.Options{Server: *server, ID: *id, Nickname: *nickname})
+	bot.OnMessage(func(m chitty.Message) {
+		if m.ClientID == bot.ID() || m.Announcement || m.Direct || strings.HasPrefix(m.Text, echoPrefix) {
+			return
+		}
+		echo := echoPrefix + m.Text
+		for utf8.RuneCountInString(echo) > 128 {
+			_, size := utf8.DecodeLastRuneInString(echo)
+			echo = echo[:len(echo)-size]
+		}
+		if err := bot.Publish(context.Background(), echo); err != nil {
+			log.Printf("could not echo message %d: %v", m.Lamport, err)
+		}
+	})
+	bot.OnJoin(func(j chitty.Join) {
+		if j.ClientID != bot.ID() {
+			bot.Publish(context.Background(), fmt.Sprintf("welcome, client-%d", j.ClientID))
+		}
+	})
+	bot.OnLeave(func(l chitty.Leave) {
+		log.Printf("client-%d %s at Lamport time %d", l.ClientID, l.Reason, l.Lamport)
+	})
+
+	if err := bot.Join(context.Background()); err != nil {
+		log.Fatalf("Could not join: %v", err)
+	}
+	log.Printf("echo bot joined as client-%d", bot.ID())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-sigChan:
+		if err := bot.Leave(context.Background()); err != nil {
+			log.Printf("could not leave: %v", err)
+		}
+	case <-bot.Done():
+		log.Print("kicked out of the chat")
+	}
+}
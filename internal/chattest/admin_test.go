@@ -0,0 +1,52 @@
+package chattest
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+// TestBroadcastReportsTheDeliveries announces to two participants and one
+// that joined without a ParticipantService, which cannot get it.
+func TestBroadcastReportsTheDeliveries(t *testing.T) {
+	h := NewConfigured(t, NewNetwork(), func(cfg *config.Server) { cfg.AdminToken = "s3cret" })
+	h.Join(1)
+	h.Join(2)
+	if _, err := h.Server.ParticipantJoins(context.Background(), &proto.ClientInfo{ClientId: 3, Address: "nowhere"}); err != nil {
+		t.Fatalf("participant 3 could not join: %v", err)
+	}
+
+	conn, err := grpc.Dial(ServerAddress, append(h.Transport.DialOptions("admin"), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	admin := proto.NewAdminServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+
+	for _, announcement := range []string{"", strings.Repeat("é", 129)} {
+		if _, err := admin.Broadcast(ctx, &proto.AdminRequest{Message: announcement}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("announcing %q returned %v, want InvalidArgument", announcement, err)
+		}
+	}
+
+	info, err := admin.Broadcast(ctx, &proto.AdminRequest{Message: strings.Repeat("é", 128)})
+	if err != nil {
+		t.Fatalf("could not announce: %v", err)
+	}
+	if info.Delivered != 2 || info.Recipients != 3 {
+		t.Errorf("the announcement was delivered to %d/%d participants, want 2/3", info.Delivered, info.Recipients)
+	}
+	for _, id := range []int{1, 2} {
+		if received := h.Received(Host(id), "broadcast"); len(received) != 1 {
+			t.Errorf("%s received %d messages, want the announcement", Host(id), len(received))
+		}
+	}
+}
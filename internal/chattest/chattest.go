@@ -0,0 +1,315 @@
+// Package chattest runs a Chitty-Chat server and its participants in one
+// process for tests. Every process listens on an in-memory bufconn listener
+// registered under its name ("server", "client-1", ...) and dials the others
+// by name, so no ports are opened. What each process sent and received is read
+// back from its event log.
+package chattest
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/client"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/eventlog"
+	"github.com/Tien197/Chitty-Chat/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ServerAddress is the name the server listens under.
+const ServerAddress = "server"
+
+const bufferSize = 1 << 20
+
+// Network is a set of named in-memory listeners.
+type Network struct {
+	mu        sync.Mutex
+	listeners map[string]*bufconn.Listener
+}
+
+// NewNetwork returns a network without listeners.
+func NewNetwork() *Network {
+	return &Network{listeners: make(map[string]*bufconn.Listener)}
+}
+
+// Listen registers a listener under name, replacing any earlier one.
+func (n *Network) Listen(name string) net.Listener {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	listener := bufconn.Listen(bufferSize)
+	n.listeners[name] = listener
+	return listener
+}
+
+// Dial connects to the listener registered under address.
+func (n *Network) Dial(ctx context.Context, address string) (net.Conn, error) {
+	n.mu.Lock()
+	listener, ok := n.listeners[address]
+	n.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("chattest: nothing listens at %q", address)
+	}
+	return listener.DialContext(ctx)
+}
+
+// DialOptions make the gRPC clients of every process dial through the network.
+func (n *Network) DialOptions(from string) []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithContextDialer(n.Dial)}
+}
+
+// Transport connects the processes of a Harness. Network is the plain one;
+// simnet.Net adds faults.
+type Transport interface {
+	// Listen returns the listener of the process called name.
+	Listen(name string) net.Listener
+	// DialOptions are given to the process called from for dialing the others.
+	DialOptions(from string) []grpc.DialOption
+}
+
+// Harness is a running server and the participants started with Join.
+type Harness struct {
+	t         testing.TB
+	dir       string
+	Transport Transport
+	Server    *server.Server
+
+	mu      sync.Mutex
+	clients map[int]*client.Client
+}
+
+// New starts a server on a fresh Network. It is stopped, together with the
+// participants still in the chat, when the test ends.
+func New(t testing.TB) *Harness {
+	t.Helper()
+	return NewWith(t, NewNetwork())
+}
+
+// NewWith starts a server on transport, like New.
+func NewWith(t testing.TB, transport Transport) *Harness {
+	t.Helper()
+	return NewConfigured(t, transport, nil)
+}
+
+// NewConfigured starts a server on transport after configure, unless nil, has
+// adjusted its settings, e.g. to enable the AdminService.
+func NewConfigured(t testing.TB, transport Transport, configure func(*config.Server)) *Harness {
+	t.Helper()
+
+	h := &Harness{
+		t:         t,
+		dir:       t.TempDir(),
+		Transport: transport,
+		clients:   make(map[int]*client.Client),
+	}
+
+	var cfg config.Server
+	cfg.EventLog = h.eventLog(ServerAddress)
+	cfg.Timeouts.Delivery = 5 * time.Second
+	if configure != nil {
+		configure(&cfg)
+	}
+	s, err := server.New(server.Options{
+		Server:      cfg,
+		Listener:    transport.Listen(ServerAddress),
+		DialOptions: transport.DialOptions(ServerAddress),
+	})
+	if err != nil {
+		t.Fatalf("could not create the server: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("could not start the server: %v", err)
+	}
+	h.Server = s
+
+	t.Cleanup(func() {
+		h.mu.Lock()
+		clients := make([]*client.Client, 0, len(h.clients))
+		for _, c := range h.clients {
+			clients = append(clients, c)
+		}
+		h.mu.Unlock()
+		for _, c := range clients {
+			c.Stop()
+		}
+		s.Stop()
+	})
+	return h
+}
+
+// Join starts participant id, which returns once the server has broadcast its join.
+func (h *Harness) Join(id int) *client.Client {
+	h.t.Helper()
+
+	c, err := h.Start(id, nil)
+	if err != nil {
+		h.t.Fatalf("participant %d could not join: %v", id, err)
+	}
+	return c
+}
+
+// Start starts participant id after configure, unless nil, has adjusted its
+// settings, e.g. to turn on mutex mode, and returns the error of joining.
+func (h *Harness) Start(id int, configure func(*config.Client)) (*client.Client, error) {
+	name := Host(id)
+
+	var cfg config.Client
+	cfg.ID = id
+	cfg.Advertise = name
+	cfg.Server.Address = ServerAddress
+	cfg.EventLog = h.eventLog(name)
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	c, err := client.New(client.Options{
+		Client:      cfg,
+		Listener:    h.Transport.Listen(name),
+		DialOptions: h.Transport.DialOptions(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.clients[id] = c
+	h.mu.Unlock()
+	return c, nil
+}
+
+// Leave stops participant id, which returns once the server has broadcast that it left.
+func (h *Harness) Leave(id int) error {
+	h.mu.Lock()
+	c, ok := h.clients[id]
+	delete(h.clients, id)
+	h.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("participant %d has not joined", id)
+	}
+	if err := c.Stop(); err != nil {
+		return fmt.Errorf("participant %d could not leave: %w", id, err)
+	}
+	return nil
+}
+
+// Client returns participant id, or nil if it is not in the chat.
+func (h *Harness) Client(id int) *client.Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.clients[id]
+}
+
+// Host is the name participant id has in the event logs and on the network.
+func Host(id int) string {
+	return fmt.Sprintf("client-%d", id)
+}
+
+func (h *Harness) eventLog(host string) string {
+	return filepath.Join(h.dir, host+".log")
+}
+
+// Events returns the events host has logged so far.
+func (h *Harness) Events(host string) []eventlog.Event {
+	h.t.Helper()
+
+	events, err := eventlog.ReadFile(h.eventLog(host))
+	if err != nil {
+		h.t.Fatalf("could not read the event log: %v", err)
+	}
+	return events
+}
+
+// Received returns the events of the given kind that host received.
+func (h *Harness) Received(host, kind string) []eventlog.Event {
+	h.t.Helper()
+
+	var received []eventlog.Event
+	for _, event := range h.Events(host) {
+		if event.Dir == "from" && event.Kind == kind {
+			received = append(received, event)
+		}
+	}
+	return received
+}
+
+// AllEvents returns the events of every process that has logged, sorted with
+// eventlog.Sort. The logs are read one after the other, so a process that still
+// runs may log a send after its log was read and before its receive was.
+func (h *Harness) AllEvents() []eventlog.Event {
+	h.t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(h.dir, "*.log"))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	sort.Strings(paths)
+
+	var events []eventlog.Event
+	for _, path := range paths {
+		logged, err := eventlog.ReadFile(path)
+		if err != nil {
+			h.t.Fatalf("could not read the event log: %v", err)
+		}
+		events = append(events, logged...)
+	}
+	eventlog.Sort(events)
+	return events
+}
+
+// CheckClockCondition reports every message whose receive does not carry a
+// later Lamport time than its send, and every receive without a logged send,
+// which it could not check. Sends without a receive were lost or given up on.
+func CheckClockCondition(t testing.TB, events []eventlog.Event) {
+	t.Helper()
+
+	matched := 0
+	for _, message := range eventlog.Match(events) {
+		if message.Receive == nil {
+			continue
+		}
+		if message.Send == nil {
+			t.Errorf("%s received %s from %s at Lamport time %d, which no send in the logs matches",
+				message.Receive.Host, message.Receive.Kind, message.Receive.Peer, message.Receive.Lamport)
+			continue
+		}
+		matched++
+		if message.Receive.Lamport <= message.Send.Lamport {
+			t.Errorf("%s received %s at Lamport time %d, not after %s sent it at %d",
+				message.Receive.Host, message.Send.Kind, message.Receive.Lamport, message.Send.Host, message.Send.Lamport)
+		}
+	}
+	if matched == 0 {
+		t.Error("the logs hold no received message to check")
+	}
+}
+
+// CheckProcessOrder reports every process whose Lamport times do not strictly
+// increase in the order it logged its events, which its own entry of the
+// vector clock gives.
+func CheckProcessOrder(t testing.TB, events []eventlog.Event) {
+	t.Helper()
+
+	byHost := make(map[string][]eventlog.Event)
+	for _, event := range events {
+		byHost[event.Host] = append(byHost[event.Host], event)
+	}
+	for host, logged := range byHost {
+		sort.Slice(logged, func(i, j int) bool { return logged[i].Clock[host] < logged[j].Clock[host] })
+		for i := 1; i < len(logged); i++ {
+			if logged[i].Lamport <= logged[i-1].Lamport {
+				t.Errorf("%s logged %s at Lamport time %d after %s at %d",
+					host, logged[i].Kind, logged[i].Lamport, logged[i-1].Kind, logged[i-1].Lamport)
+			}
+		}
+	}
+}
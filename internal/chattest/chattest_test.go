@@ -0,0 +1,204 @@
+package chattest
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func TestJoinIsBroadcastToEveryone(t *testing.T) {
+	h := New(t)
+	for id := 1; id <= 3; id++ {
+		h.Join(id)
+	}
+
+	// participant i hears about itself and everyone who joined after it
+	for id := 1; id <= 3; id++ {
+		var joined []string
+		for _, event := range h.Received(Host(id), "join-broadcast") {
+			joined = append(joined, event.Text)
+		}
+		var want []string
+		for newcomer := id; newcomer <= 3; newcomer++ {
+			want = append(want, fmt.Sprintf("Client %d joined", newcomer))
+		}
+		if strings.Join(joined, "; ") != strings.Join(want, "; ") {
+			t.Errorf("%s received joins %q, want %q", Host(id), joined, want)
+		}
+	}
+}
+
+func TestMessageReachesEveryone(t *testing.T) {
+	h := New(t)
+	for id := 1; id <= 3; id++ {
+		h.Join(id)
+	}
+
+	if err := h.Client(2).Publish("hello"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+
+	want := `received message "hello" from Participant 2`
+	for id := 1; id <= 3; id++ {
+		received := h.Received(Host(id), "broadcast")
+		if len(received) != 1 || !strings.HasSuffix(received[0].Text, want) {
+			t.Errorf("%s received %v, want one message ending in %q", Host(id), received, want)
+		}
+	}
+}
+
+func TestLeaveReachesTheRemainingParticipants(t *testing.T) {
+	h := New(t)
+	for id := 1; id <= 3; id++ {
+		h.Join(id)
+	}
+
+	if err := h.Leave(2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int{1, 3} {
+		received := h.Received(Host(id), "leave-broadcast")
+		if len(received) != 1 || received[0].Text != "Participant 2 left" {
+			t.Errorf("%s received %v, want that participant 2 left", Host(id), received)
+		}
+	}
+	if received := h.Received(Host(2), "leave-broadcast"); len(received) != 0 {
+		t.Errorf("the participant that left received %v", received)
+	}
+}
+
+func TestConfiguredNicknameAndRoom(t *testing.T) {
+	h := New(t)
+	if _, err := h.Start(1, func(cfg *config.Client) { cfg.Nickname, cfg.Room = "alice", "dev" }); err != nil {
+		t.Fatalf("participant 1 could not join: %v", err)
+	}
+
+	list, err := h.Server.Participants(context.Background(), &proto.ClientInfo{ClientId: 1})
+	if err != nil {
+		t.Fatalf("could not list the participants: %v", err)
+	}
+	if len(list.Participants) != 1 || list.Participants[0].Nickname != "alice" || list.Participants[0].Room != "dev" {
+		t.Errorf("the server lists %v, want alice in dev", list.Participants)
+	}
+}
+
+func TestLamportOnlyClock(t *testing.T) {
+	h := New(t)
+	if _, err := h.Start(1, func(cfg *config.Client) { cfg.Clock = config.LamportOnly }); err != nil {
+		t.Fatalf("participant 1 could not join: %v", err)
+	}
+	if err := h.Client(1).Publish("no vector clock"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+
+	publishes := h.Received(ServerAddress, "publish")
+	if len(publishes) != 1 {
+		t.Fatalf("the server received %d publishes, want 1", len(publishes))
+	}
+	if _, ok := publishes[0].Clock[Host(1)]; ok {
+		t.Errorf("the server merged the vector clock %v of a participant without one", publishes[0].Clock)
+	}
+	events := h.AllEvents()
+	CheckClockCondition(t, events)
+	CheckProcessOrder(t, events)
+}
+
+func TestRepeatedKickIsIgnored(t *testing.T) {
+	h := New(t)
+	c := h.Join(1)
+
+	// an at-least-once transport may deliver the kick broadcast twice
+	kick := &proto.ClientInfo{ClientId: 1, Message: "was kicked"}
+	for i := 0; i < 2; i++ {
+		if _, err := c.ClientLeaveReturn(context.Background(), kick); err != nil {
+			t.Fatalf("could not deliver the kick: %v", err)
+		}
+	}
+	select {
+	case <-c.Kicked():
+	default:
+		t.Error("the participant does not know it was kicked")
+	}
+}
+
+func TestLamportClockCondition(t *testing.T) {
+	h := New(t)
+	for id := 1; id <= 4; id++ {
+		h.Join(id)
+	}
+	for id := 1; id <= 4; id++ {
+		if err := h.Client(id).Publish(fmt.Sprintf("message %d", id)); err != nil {
+			t.Fatalf("could not publish: %v", err)
+		}
+	}
+	if err := h.Leave(3); err != nil {
+		t.Fatal(err)
+	}
+
+	events := h.AllEvents()
+	var receives int
+	for _, event := range events {
+		if event.Dir == "from" {
+			receives++
+		}
+	}
+	if receives == 0 {
+		t.Fatal("no receive was logged")
+	}
+	CheckClockCondition(t, events)
+}
+
+// TestMessageLimitCountsCharacters publishes messages of two-byte characters,
+// which the client and the server count like the TUI does.
+func TestMessageLimitCountsCharacters(t *testing.T) {
+	h := New(t)
+	c := h.Join(1)
+
+	if err := c.Publish(strings.Repeat("é", 128)); err != nil {
+		t.Errorf("could not publish 128 characters: %v", err)
+	}
+	if err := c.Publish(strings.Repeat("é", 129)); err == nil {
+		t.Error("published 129 characters")
+	}
+	if received := h.Received(Host(1), "broadcast"); len(received) != 1 {
+		t.Errorf("participant 1 received %d messages, want the one of 128 characters", len(received))
+	}
+}
+
+func TestJoinChecksTheIDAndRoom(t *testing.T) {
+	h := New(t)
+	h.Join(1)
+
+	if _, err := h.Server.ParticipantJoins(context.Background(), &proto.ClientInfo{ClientId: 1, Address: Host(1)}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("joining again as participant 1 returned %v, want AlreadyExists", err)
+	}
+	if _, err := h.Server.ParticipantJoins(context.Background(), &proto.ClientInfo{ClientId: 2, Room: "two words"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("joining room \"two words\" returned %v, want InvalidArgument", err)
+	}
+	// a REST caller without a ParticipantService
+	if _, err := h.Server.ParticipantJoins(context.Background(), &proto.ClientInfo{ClientId: 3, Room: " dev "}); err != nil {
+		t.Fatalf("participant 3 could not join: %v", err)
+	}
+
+	list, err := h.Server.Participants(context.Background(), &proto.ClientInfo{ClientId: 1})
+	if err != nil {
+		t.Fatalf("could not list the participants: %v", err)
+	}
+	if len(list.Participants) != 2 || list.Participants[1].ClientId != 3 || list.Participants[1].Room != "dev" {
+		t.Errorf("the server lists %v, want participants 1 and 3, in dev", list.Participants)
+	}
+	for _, event := range h.Events(ServerAddress) {
+		if event.Kind == "join-broadcast" && event.Peer == Host(3) {
+			t.Errorf("the server sent %q to participant 3, which has no ParticipantService", event.Text)
+		}
+	}
+	if received := h.Received(Host(1), "join-broadcast"); len(received) != 2 {
+		t.Errorf("participant 1 received %d join broadcasts, want its own and participant 3's", len(received))
+	}
+}
@@ -0,0 +1,52 @@
+package chattest
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCommands runs the commands of participant 1 in order, each seeing what
+// the ones before it changed, and checks what they show or the error.
+func TestCommands(t *testing.T) {
+	h := New(t)
+	c := h.Join(1)
+	h.Join(2)
+
+	for _, test := range []struct {
+		line    string
+		want    string // contained in the output
+		wantErr string // contained in the error, none when empty
+	}{
+		{line: "/bogus", wantErr: "unknown command /bogus, try /help"},
+		{line: "/help", want: "/dm <id> <message>"},
+		{line: "/nick", wantErr: "usage: /nick <name>"},
+		{line: "/nick alice", want: "you are now alice"},
+		{line: "/join", wantErr: "usage: /join <room>"},
+		{line: "/join   dev  ", want: "you are now in dev"},
+		{line: "/who", want: "2 in the chat: alice (client-1) in dev, client-2 in lobby"},
+		{line: "/history 0", wantErr: "usage: /history [N]"},
+		{line: "/history x", wantErr: "usage: /history [N]"},
+		{line: "/history", want: "no messages yet"},
+		{line: "/dm", wantErr: "usage: /dm <id> <message>"},
+		{line: "/dm two hi", wantErr: "usage: /dm <id> <message>"},
+		{line: "/dm 2", wantErr: "usage: /dm <id> <message>"},
+		{line: "/dm 2 hi bob", want: "you → client-2: hi bob"},
+		{line: "/dm 9 hi", wantErr: "participant 9"},
+		{line: "/clock", want: "Lamport time"},
+		{line: "/leave", wantErr: "leaving the chat"},
+	} {
+		got, err := c.RunCommand(test.line)
+		switch {
+		case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+			t.Errorf("%q returned the error %v, want %q", test.line, err, test.wantErr)
+		case test.wantErr == "" && err != nil:
+			t.Errorf("%q failed: %v", test.line, err)
+		case !strings.Contains(got, test.want):
+			t.Errorf("%q showed %q, want %q", test.line, got, test.want)
+		}
+	}
+
+	if received := h.Received(Host(2), "broadcast"); len(received) != 1 || !strings.Contains(received[0].Text, "hi bob") {
+		t.Errorf("participant 2 received %v, want the direct message", received)
+	}
+}
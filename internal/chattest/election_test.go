@@ -0,0 +1,40 @@
+package chattest
+
+import (
+	"github.com/Tien197/Chitty-Chat/config"
+	"testing"
+	"time"
+)
+
+// TestElectionSkipsParticipantsWithoutElections starts one electing
+// participant between two that do not take part: it has to skip them and
+// become the moderator itself instead of waiting for them.
+func TestElectionSkipsParticipantsWithoutElections(t *testing.T) {
+	for _, mode := range []string{"bully", "ring"} {
+		t.Run(mode, func(t *testing.T) {
+			h := New(t)
+			h.Join(1)
+			h.Join(3)
+			if _, err := h.Start(2, func(cfg *config.Client) { cfg.Election = mode }); err != nil {
+				t.Fatalf("participant 2 could not join: %v", err)
+			}
+
+			moderator := func() bool {
+				for _, event := range h.Events(Host(2)) {
+					if event.Kind == "moderator" {
+						return true
+					}
+				}
+				return false
+			}
+			// the election runs in the background once participant 2 has joined
+			deadline := time.Now().Add(2 * time.Second)
+			for !moderator() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if !moderator() {
+				t.Errorf("participant 2 did not become the moderator")
+			}
+		})
+	}
+}
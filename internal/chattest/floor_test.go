@@ -0,0 +1,46 @@
+package chattest
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+// TestServerKeepsTheFloor lets participant 1 take the floor at the server
+// directly: nobody else may publish until it releases it.
+func TestServerKeepsTheFloor(t *testing.T) {
+	h := New(t)
+	h.Join(1)
+	h.Join(2)
+
+	conn, err := grpc.Dial(ServerAddress, append(h.Transport.DialOptions(Host(1)), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	server := proto.NewCCServiceClient(conn)
+
+	if _, err := server.TakeFloor(context.Background(), &proto.ClientInfo{ClientId: 1}); err != nil {
+		t.Fatalf("participant 1 could not take the floor: %v", err)
+	}
+	if _, err := server.TakeFloor(context.Background(), &proto.ClientInfo{ClientId: 2}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("taking the held floor returned %v, want FailedPrecondition", err)
+	}
+	if err := h.Client(2).Publish("out of turn"); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("publishing without the floor returned %v, want FailedPrecondition", err)
+	}
+	if err := h.Client(1).Publish("my turn"); err != nil {
+		t.Errorf("the holder could not publish: %v", err)
+	}
+
+	if _, err := server.ReleaseFloor(context.Background(), &proto.ClientInfo{ClientId: 1}); err != nil {
+		t.Fatalf("participant 1 could not release the floor: %v", err)
+	}
+	if err := h.Client(2).Publish("after"); err != nil {
+		t.Errorf("could not publish once the floor was free: %v", err)
+	}
+}
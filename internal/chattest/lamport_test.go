@@ -0,0 +1,113 @@
+package chattest
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+var seed = flag.Int64("seed", 0, "seed of the random runs; a new one each time when 0")
+
+// TestLamportInvariantsUnderRandomRuns lets several workers join, publish and
+// leave at random at the same time and checks the event logs of all processes:
+// every receive is later than its send, and every process's Lamport times
+// strictly increase. The participants of the odd workers request the floor
+// before publishing, so the others' publishes may be rejected while it is
+// held, and those of worker 2 elect a moderator, by the bully
+// algorithm in even runs and the ring in odd ones. A failing run is replayed
+// with -seed, although the goroutines may still interleave differently.
+func TestLamportInvariantsUnderRandomRuns(t *testing.T) {
+	runs, workers, steps := 5, 4, 20
+	if testing.Short() {
+		runs, steps = 2, 10
+	}
+
+	base := *seed
+	if base == 0 {
+		base = time.Now().UnixNano()
+	}
+	t.Logf("seed %d", base)
+
+	for run := 0; run < runs; run++ {
+		run := run
+		t.Run(fmt.Sprintf("run-%d", run), func(t *testing.T) {
+			h := New(t)
+
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					randomSteps(t, h, rand.New(rand.NewSource(base+int64(run*workers+w))), w, workers, steps, mode(run, w))
+				}(w)
+			}
+			wg.Wait()
+
+			events := h.AllEvents()
+			CheckClockCondition(t, events)
+			CheckProcessOrder(t, events)
+		})
+	}
+}
+
+// mode returns the settings of the participants of worker w in the given run
+func mode(run, w int) func(*config.Client) {
+	switch {
+	case w%2 == 1:
+		return func(cfg *config.Client) { cfg.Mutex = true }
+	case w == 2:
+		election := [...]string{"bully", "ring"}[run%2]
+		return func(cfg *config.Client) { cfg.Election = election }
+	}
+	return nil
+}
+
+// randomSteps joins, publishes from and stops participants of its own, started
+// with configure, and stops those left at the end, so that the event logs are
+// complete. Worker w of n uses the ids w+1, w+1+n, ... so that no id is
+// reused: a new process would start its clock and event log over. It runs on
+// a goroutine of its own and reports failures with t.Errorf.
+func randomSteps(t *testing.T, h *Harness, r *rand.Rand, w, n, steps int, configure func(*config.Client)) {
+	var cfg config.Client
+	if configure != nil {
+		configure(&cfg)
+	}
+	next := w + 1
+	var joined []int
+	defer func() {
+		for _, id := range joined {
+			if err := h.Leave(id); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for step := 0; step < steps; step++ {
+		switch action := r.Intn(10); {
+		case len(joined) == 0 || action < 3:
+			if _, err := h.Start(next, configure); err != nil {
+				t.Errorf("participant %d could not join: %v", next, err)
+				return
+			}
+			joined = append(joined, next)
+			next += n
+		case action < 8:
+			id := joined[r.Intn(len(joined))]
+			err := h.Client(id).Publish(fmt.Sprintf("step %d of participant %d", step, id))
+			if err != nil && (cfg.Mutex || status.Code(err) != codes.FailedPrecondition) {
+				t.Errorf("participant %d could not publish: %v", id, err)
+			}
+		default:
+			i := r.Intn(len(joined))
+			if err := h.Leave(joined[i]); err != nil {
+				t.Error(err)
+			}
+			joined = append(joined[:i], joined[i+1:]...)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package chattest
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"github.com/Tien197/Chitty-Chat/server"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startServer starts a server on a free port with the metrics served at metrics
+func startServer(t *testing.T, metrics string) (*server.Server, error) {
+	var cfg config.Server
+	cfg.Metrics = metrics
+	cfg.EventLog = filepath.Join(t.TempDir(), "server.log")
+	s, err := server.New(server.Options{Server: cfg})
+	if err != nil {
+		t.Fatalf("could not create the server: %v", err)
+	}
+	return s, s.Start()
+}
+
+func TestStopReleasesTheMetricsAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	// an embedding program may start and stop a server again and again
+	for i := 0; i < 2; i++ {
+		s, err := startServer(t, address)
+		if err != nil {
+			t.Fatalf("could not start the server a %d. time: %v", i+1, err)
+		}
+		if err := s.Stop(); err != nil {
+			t.Errorf("could not stop the server: %v", err)
+		}
+	}
+}
+
+func TestStartFailsOnABusyMetricsAddress(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	if s, err := startServer(t, busy.Addr().String()); err == nil {
+		s.Stop()
+		t.Error("the server started although its metrics address is in use")
+	}
+
+	h := New(t)
+	if _, err := h.Start(1, func(cfg *config.Client) { cfg.Metrics = busy.Addr().String() }); err == nil {
+		t.Error("the participant started although its metrics address is in use")
+	}
+}
+
+// TestFailedStartClosesTheWebhooks queues a webhook event before a start that
+// fails: closing the dispatcher delivers it before Start returns.
+func TestFailedStartClosesTheWebhooks(t *testing.T) {
+	var delivered atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		delivered.Add(1)
+	}))
+	defer hook.Close()
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	var cfg config.Server
+	cfg.Metrics = busy.Addr().String()
+	cfg.EventLog = filepath.Join(t.TempDir(), "server.log")
+	cfg.Webhooks.Hooks = []config.Webhook{{URL: hook.URL}}
+	s, err := server.New(server.Options{Server: cfg})
+	if err != nil {
+		t.Fatalf("could not create the server: %v", err)
+	}
+	if _, err := s.ParticipantJoins(context.Background(), &proto.ClientInfo{ClientId: 1, Address: "nowhere"}); err != nil {
+		t.Fatalf("participant 1 could not join: %v", err)
+	}
+
+	if err := s.Start(); err == nil {
+		s.Stop()
+		t.Fatal("the server started although its metrics address is in use")
+	}
+	if delivered.Load() != 1 {
+		t.Error("the failed start did not close the webhooks")
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("could not stop the server after the failed start: %v", err)
+	}
+}
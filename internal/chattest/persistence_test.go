@@ -0,0 +1,60 @@
+package chattest
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"github.com/Tien197/Chitty-Chat/server"
+	"path/filepath"
+	"testing"
+)
+
+// TestHistorySurvivesARestart publishes to a server with a data directory,
+// stops it and asks a new server on the same directory for the history.
+func TestHistorySurvivesARestart(t *testing.T) {
+	dir := t.TempDir()
+	start := func() *server.Server {
+		var cfg config.Server
+		cfg.DataDir = dir
+		cfg.EventLog = filepath.Join(t.TempDir(), "server.log")
+		s, err := server.New(server.Options{Server: cfg})
+		if err != nil {
+			t.Fatalf("could not create the server: %v", err)
+		}
+		if _, err := s.ParticipantJoins(context.Background(), &proto.ClientInfo{ClientId: 1}); err != nil {
+			t.Fatalf("participant 1 could not join: %v", err)
+		}
+		return s
+	}
+
+	s := start()
+	for i := 0; i < 3; i++ {
+		if _, err := s.ParticipantMessages(context.Background(), &proto.ClientInfo{ClientId: 1, Message: fmt.Sprint("kept ", i)}); err != nil {
+			t.Fatalf("could not publish: %v", err)
+		}
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("could not stop the server: %v", err)
+	}
+
+	s = start()
+	defer s.Stop()
+	history, err := s.History(context.Background(), &proto.HistoryRequest{ClientId: 1, Count: 10})
+	if err != nil {
+		t.Fatalf("could not get the history: %v", err)
+	}
+	if len(history.Messages) != 3 {
+		t.Fatalf("the restarted server kept %d messages, want 3", len(history.Messages))
+	}
+	var last int64
+	for i, message := range history.Messages {
+		if want := fmt.Sprint("kept ", i); message.Message != want || message.Room != "lobby" {
+			t.Errorf("message %d is %q in %s, want %q in the lobby", i, message.Message, message.Room, want)
+		}
+		last = message.LamportTime
+	}
+	if history.LamportTime <= last {
+		t.Errorf("the restarted server's clock %d is not later than the kept message at %d", history.LamportTime, last)
+	}
+}
@@ -0,0 +1,62 @@
+package chattest
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublishReportsDeliveries(t *testing.T) {
+	h := New(t)
+	for id := 1; id <= 3; id++ {
+		h.Join(id)
+	}
+
+	reply, err := h.Server.ParticipantMessages(context.Background(), &proto.ClientInfo{ClientId: 1, Message: "counted", MessageId: "m1"})
+	if err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+	if reply.Delivered != 3 || reply.Recipients != 3 {
+		t.Errorf("delivered to %d/%d participants, want 3/3", reply.Delivered, reply.Recipients)
+	}
+}
+
+func TestReadReceiptsReachThePublisher(t *testing.T) {
+	h := New(t)
+	h.Join(1)
+	if _, err := h.Start(2, func(cfg *config.Client) { cfg.Receipts = true }); err != nil {
+		t.Fatalf("participant 2 could not join: %v", err)
+	}
+	h.Join(3) // does not send receipts
+
+	if err := h.Client(1).Publish("read me"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+
+	receipts := func() []string {
+		var texts []string
+		for _, event := range h.Received(Host(1), "read-receipt") {
+			texts = append(texts, event.Text)
+		}
+		return texts
+	}
+	// receipts are sent in the background once the message is shown
+	deadline := time.Now().Add(2 * time.Second)
+	for len(receipts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond) // a receipt from participant 3 would have arrived by now
+
+	if receipts := receipts(); len(receipts) != 1 || !strings.Contains(receipts[0], "Participant 2 read message") {
+		t.Errorf("the publisher received the receipts %q, want one from participant 2", receipts)
+	}
+	for _, id := range []int{2, 3} {
+		if got := h.Received(Host(id), "read-receipt"); len(got) != 0 {
+			t.Errorf("%s received receipts %v for a message it did not publish", Host(id), got)
+		}
+	}
+	CheckClockCondition(t, h.AllEvents())
+}
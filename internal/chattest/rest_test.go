@@ -0,0 +1,45 @@
+package chattest
+
+import (
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/server"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRESTRejectsInvalidMessages publishes through the REST gateway, which
+// reaches the server without the checks of a client.
+func TestRESTRejectsInvalidMessages(t *testing.T) {
+	var cfg config.Server
+	cfg.HTTP = "127.0.0.1:0"
+	cfg.EventLog = filepath.Join(t.TempDir(), "server.log")
+	s, err := server.New(server.Options{Server: cfg})
+	if err != nil {
+		t.Fatalf("could not create the server: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("could not start the server: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	for _, test := range []struct {
+		name, body string
+		want       int
+	}{
+		{"empty", `{"clientId": 1, "message": ""}`, http.StatusBadRequest},
+		{"too long", `{"clientId": 1, "message": "` + strings.Repeat("x", 129) + `"}`, http.StatusBadRequest},
+		{"not a participant", `{"clientId": 1, "message": "hello"}`, http.StatusNotFound},
+		{"128 two-byte characters from a stranger", `{"clientId": 1, "message": "` + strings.Repeat("é", 128) + `"}`, http.StatusNotFound},
+	} {
+		response, err := http.Post("http://"+s.RESTAddr().String()+"/v1/messages", "application/json", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("could not publish: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.want {
+			t.Errorf("publishing a message that is %s returned %s, want %d", test.name, response.Status, test.want)
+		}
+	}
+}
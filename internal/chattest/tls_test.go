@@ -0,0 +1,125 @@
+package chattest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/Tien197/Chitty-Chat/client"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/server"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for localhost and its key
+// to dir and returns their paths
+func writeCertificate(t *testing.T, dir string) (cert, key string) {
+	t.Helper()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate a key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &private.PublicKey, private)
+	if err != nil {
+		t.Fatalf("could not create the certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		t.Fatalf("could not encode the key: %v", err)
+	}
+
+	cert, key = filepath.Join(dir, "chitty.crt"), filepath.Join(dir, "chitty.key")
+	if err := os.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// TestTLS serves the chat with TLS: a participant checking the certificate
+// joins and publishes, the REST gateway reaches the server, and a participant
+// dialing without TLS does not get in.
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeCertificate(t, dir)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config.Server
+	cfg.TLS = config.TLS{Cert: cert, Key: key}
+	cfg.HTTP = "127.0.0.1:0"
+	cfg.EventLog = filepath.Join(dir, "server.log")
+	s, err := server.New(server.Options{Server: cfg, Listener: listener})
+	if err != nil {
+		t.Fatalf("could not create the server: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("could not start the server: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	participant := func(id int, useTLS bool) (*client.Client, error) {
+		var cfg config.Client
+		cfg.ID = id
+		cfg.Server.Address = listener.Addr().String()
+		cfg.Server.TLS = useTLS
+		if useTLS {
+			cfg.Server.CA = cert
+		}
+		cfg.EventLog = filepath.Join(dir, "client.log")
+		cfg.Timeouts.Connect = time.Second
+		c, err := client.New(client.Options{Client: cfg})
+		if err != nil {
+			t.Fatalf("could not create participant %d: %v", id, err)
+		}
+		return c, c.Start()
+	}
+
+	c, err := participant(1, true)
+	if err != nil {
+		t.Fatalf("participant 1 could not join over TLS: %v", err)
+	}
+	defer c.Stop()
+	if err := c.Publish("over TLS"); err != nil {
+		t.Errorf("participant 1 could not publish over TLS: %v", err)
+	}
+
+	response, err := http.Post("http://"+s.RESTAddr().String()+"/v1/messages", "application/json", strings.NewReader(`{"clientId": 1, "message": "through REST"}`))
+	if err != nil {
+		t.Fatalf("could not publish through REST: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("publishing through REST returned %s, want 200", response.Status)
+	}
+
+	if _, err := participant(2, false); err == nil {
+		t.Error("participant 2 joined without TLS")
+	}
+}
@@ -0,0 +1,113 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/dedup"
+	"github.com/Tien197/Chitty-Chat/internal/chattest"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"testing"
+	"time"
+)
+
+// The publisher loses the server's reply and publishes again with the same
+// message id; the server broadcasts the message once.
+func TestRetriedPublishIsBroadcastOnce(t *testing.T) {
+	network := New(1)
+	h := join(t, network, 3)
+
+	network.SetLink(chattest.Host(1), chattest.ServerAddress, Link{DropReply: 1})
+	err := h.Client(1).Publish("once")
+	network.SetLink(chattest.Host(1), chattest.ServerAddress, Link{})
+	if err == nil {
+		t.Fatal("publishing succeeded although every reply was lost")
+	}
+
+	var publishes int
+	for _, event := range h.Events(chattest.Host(1)) {
+		if event.Kind == "publish" && event.Dir == "to" {
+			publishes++
+		}
+	}
+	if publishes < 2 {
+		t.Errorf("the publisher sent the message %d times, want it retried", publishes)
+	}
+	for id := 1; id <= 3; id++ {
+		if got := messages(h, chattest.Host(id)); len(got) != 1 || copies(h, chattest.Host(id)) != 0 {
+			t.Errorf("%s received %q, want the message once and no copy", chattest.Host(id), got)
+		}
+	}
+}
+
+// The publisher gives up on a broadcast that takes longer than its timeout
+// and publishes again; the server still delivers the message to everyone
+// instead of answering the retry with the deliveries the first attempt had
+// made when the publisher gave up.
+func TestRetryAfterTimeoutReachesEveryone(t *testing.T) {
+	network := New(1)
+	h := chattest.NewWith(t, network)
+	if _, err := h.Start(1, func(cfg *config.Client) { cfg.Timeouts.RPC = 300 * time.Millisecond }); err != nil {
+		t.Fatalf("participant 1 could not join: %v", err)
+	}
+	h.Join(2)
+	h.Join(3)
+
+	network.SetLink(chattest.ServerAddress, chattest.Host(3), Link{Delay: 600 * time.Millisecond})
+	if err := h.Client(1).Publish("slow"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+	network.SetLink(chattest.ServerAddress, chattest.Host(3), Link{})
+
+	for id := 1; id <= 3; id++ {
+		if got := messages(h, chattest.Host(id)); len(got) != 1 {
+			t.Errorf("%s received %q, want the message once", chattest.Host(id), got)
+		}
+	}
+}
+
+// Some replies are lost, but a retry gets through: every publish succeeds and
+// is shown once.
+func TestPublishSucceedsAfterLostReply(t *testing.T) {
+	network := New(2) // loses up to two replies in a row on this link
+	h := join(t, network, 2)
+
+	network.SetLink(chattest.Host(1), chattest.ServerAddress, Link{DropReply: 0.3})
+	for i := 0; i < 5; i++ {
+		if err := h.Client(1).Publish(fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("could not publish: %v", err)
+		}
+	}
+	if len(network.Faults()) == 0 {
+		t.Fatal("no reply was lost")
+	}
+	for id := 1; id <= 2; id++ {
+		if got := messages(h, chattest.Host(id)); len(got) != 5 {
+			t.Errorf("%s received %q, want every message once", chattest.Host(id), got)
+		}
+	}
+
+	// the reply to the retry reports the deliveries of the first attempt
+	conn, err := grpc.Dial(chattest.ServerAddress, append(network.DialOptions(chattest.Host(1)), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	server := proto.NewCCServiceClient(conn)
+	publish := &proto.ClientInfo{ClientId: 1, Message: "counted", MessageId: dedup.NewID()}
+
+	network.SetLink(chattest.Host(1), chattest.ServerAddress, Link{DropReply: 1})
+	if _, err := server.ParticipantMessages(context.Background(), publish); err == nil {
+		t.Fatal("publishing succeeded although the reply was lost")
+	}
+	network.SetLink(chattest.Host(1), chattest.ServerAddress, Link{})
+	reply, err := server.ParticipantMessages(context.Background(), publish)
+	if err != nil {
+		t.Fatalf("could not publish again: %v", err)
+	}
+	if reply.Delivered != 2 || reply.Recipients != 2 {
+		t.Errorf("the retry was delivered to %d/%d participants, want 2/2", reply.Delivered, reply.Recipients)
+	}
+}
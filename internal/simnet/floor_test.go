@@ -0,0 +1,37 @@
+package simnet
+
+import (
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/internal/chattest"
+	"testing"
+	"time"
+)
+
+// A participant that does not answer a floor request in time fails the
+// publish instead of blocking it, and the floor stays free.
+func TestFloorRequestTimesOut(t *testing.T) {
+	network := New(1)
+	h := chattest.NewWith(t, network)
+	for id := 1; id <= 2; id++ {
+		if _, err := h.Start(id, func(cfg *config.Client) {
+			cfg.Mutex = true
+			cfg.Timeouts.RPC = 200 * time.Millisecond
+		}); err != nil {
+			t.Fatalf("participant %d could not join: %v", id, err)
+		}
+	}
+
+	network.SetLink(chattest.Host(1), chattest.Host(2), Link{Delay: time.Second})
+	start := time.Now()
+	if err := h.Client(1).Publish("stuck"); err == nil {
+		t.Error("publishing succeeded without a floor reply")
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("the publish waited %v for the floor reply", elapsed)
+	}
+	network.SetLink(chattest.Host(1), chattest.Host(2), Link{})
+
+	if err := h.Client(2).Publish("free"); err != nil {
+		t.Errorf("could not publish after the failed request: %v", err)
+	}
+}
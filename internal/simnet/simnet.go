@@ -0,0 +1,227 @@
+// Package simnet is a simulated network for tests. It connects the processes
+// of a chattest.Harness in memory like chattest.Network, and injects faults
+// into the calls on each link, from one process to another: delays, lost
+// requests and replies, duplicated requests, calls overtaken by later ones and
+// partitions.
+//
+// Faults are decided per call, not per byte, so that gRPC's framing stays
+// intact. Every link draws from its own random source, seeded from the seed of
+// the network and the link's name, so a run that makes the same calls on a
+// link in the same order gets the same faults again, however the goroutines of
+// other links are scheduled.
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/internal/chattest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"path"
+	"sync"
+	"time"
+)
+
+// Link describes the faults on the calls from one process to another.
+type Link struct {
+	Delay     time.Duration // added to every call
+	Jitter    time.Duration // up to this much more, at random
+	Drop      float64       // probability that a request is lost
+	DropReply float64       // probability that a request is handled but its reply is lost
+	Duplicate float64       // probability that a request is delivered twice
+	Reorder   float64       // probability that a call is held back for Hold, so later calls overtake it
+	Hold      time.Duration // 50ms when zero
+}
+
+// Fault is a fault injected into one call.
+type Fault struct {
+	From, To string
+	Method   string // without the service, e.g. ClientMessageReturn
+	Kind     string // drop, drop-reply, duplicate, reorder or partition
+}
+
+func (f Fault) String() string {
+	return fmt.Sprintf("%s -> %s %s: %s", f.From, f.To, f.Method, f.Kind)
+}
+
+// Net is a simulated network. The zero Link applies between processes that
+// have not been given one.
+type Net struct {
+	network *chattest.Network
+	seed    int64
+
+	mu       sync.Mutex
+	links    map[[2]string]Link
+	sources  map[[2]string]*rand.Rand
+	isolated map[string]bool // one side of the partition; nil when there is none
+	faults   []Fault
+}
+
+// New returns a network without faults whose random choices derive from seed.
+func New(seed int64) *Net {
+	return &Net{
+		network: chattest.NewNetwork(),
+		seed:    seed,
+		links:   make(map[[2]string]Link),
+		sources: make(map[[2]string]*rand.Rand),
+	}
+}
+
+// Listen returns the listener of the process called name.
+func (n *Net) Listen(name string) net.Listener {
+	return n.network.Listen(name)
+}
+
+// DialOptions make the process called from dial through the network and
+// inject the faults of its links into its calls.
+func (n *Net) DialOptions(from string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(n.network.Dial),
+		grpc.WithChainUnaryInterceptor(n.interceptor(from)),
+	}
+}
+
+// SetLink sets the faults on the calls from one process to another.
+func (n *Net) SetLink(from, to string, link Link) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.links[[2]string{from, to}] = link
+}
+
+// Between sets the faults on the calls in both directions between a and b.
+func (n *Net) Between(a, b string, link Link) {
+	n.SetLink(a, b, link)
+	n.SetLink(b, a, link)
+}
+
+// Partition splits the network in two: the named processes reach each other
+// but none of the others, which keep reaching each other. A new partition
+// replaces the last one.
+func (n *Net) Partition(names ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.isolated = make(map[string]bool, len(names))
+	for _, name := range names {
+		n.isolated[name] = true
+	}
+}
+
+// Heal ends the partition.
+func (n *Net) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.isolated = nil
+}
+
+// Faults returns the faults injected so far, in the order they were decided.
+func (n *Net) Faults() []Fault {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]Fault(nil), n.faults...)
+}
+
+// decision is what happens to one call
+type decision struct {
+	delay                                   time.Duration
+	partitioned, drop, dropReply, dup, hold bool
+}
+
+// decide draws the faults of a call from the link's own random source. The
+// same values are drawn for every call, whatever the link's settings, so that
+// changing one probability does not shift the others.
+func (n *Net) decide(from, to, method string) decision {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := [2]string{from, to}
+	link := n.links[key]
+	source, ok := n.sources[key]
+	if !ok {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s->%s", from, to)
+		source = rand.New(rand.NewSource(n.seed ^ int64(h.Sum64())))
+		n.sources[key] = source
+	}
+
+	jitter, drop, dropReply, dup, hold := source.Float64(), source.Float64(), source.Float64(), source.Float64(), source.Float64()
+	d := decision{
+		delay:       link.Delay + time.Duration(jitter*float64(link.Jitter)),
+		partitioned: n.isolated != nil && n.isolated[from] != n.isolated[to],
+		drop:        drop < link.Drop,
+		dropReply:   dropReply < link.DropReply,
+		dup:         dup < link.Duplicate,
+		hold:        hold < link.Reorder,
+	}
+	if d.hold {
+		d.delay += link.Hold
+		if link.Hold == 0 {
+			d.delay += 50 * time.Millisecond
+		}
+	}
+
+	record := func(kind string) {
+		n.faults = append(n.faults, Fault{From: from, To: to, Method: method, Kind: kind})
+	}
+	switch {
+	case d.partitioned:
+		record("partition")
+		return d
+	case d.drop:
+		record("drop")
+		return d
+	}
+	if d.hold {
+		record("reorder")
+	}
+	if d.dup {
+		record("duplicate")
+	}
+	if d.dropReply {
+		record("drop-reply")
+	}
+	return d
+}
+
+// interceptor injects the faults into the calls the process called from makes
+func (n *Net) interceptor(from string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		to, name := cc.Target(), path.Base(method)
+		d := n.decide(from, to, name)
+
+		if d.partitioned {
+			return status.Errorf(codes.Unavailable, "simnet: %s cannot reach %s", from, to)
+		}
+		if d.delay > 0 {
+			timer := time.NewTimer(d.delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return status.FromContextError(ctx.Err()).Err()
+			}
+		}
+		if d.drop {
+			return status.Errorf(codes.Unavailable, "simnet: %s to %s was lost", name, to)
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if d.dup {
+			// the copy is handled too, but only the first reply reaches the caller
+			duplicate := protobuf.Clone(reply.(protobuf.Message))
+			invoker(ctx, method, req, duplicate, cc, opts...)
+		}
+		if err == nil && d.dropReply {
+			return status.Errorf(codes.Unavailable, "simnet: the reply of %s from %s was lost", name, to)
+		}
+		return err
+	}
+}
@@ -0,0 +1,172 @@
+package simnet
+
+import (
+	"context"
+	"github.com/Tien197/Chitty-Chat/internal/chattest"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// messages returns the texts of the messages host received, in the order it
+// logged them, leaving out the copies it dropped
+func messages(h *chattest.Harness, host string) []string {
+	var texts []string
+	for _, event := range h.Received(host, "broadcast") {
+		if _, text, ok := strings.Cut(event.Text, "received message "); ok {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// copies returns the number of copies of messages host dropped
+func copies(h *chattest.Harness, host string) int {
+	var dropped int
+	for _, event := range h.Received(host, "broadcast") {
+		if strings.Contains(event.Text, "dropped a copy") {
+			dropped++
+		}
+	}
+	return dropped
+}
+
+func join(t *testing.T, network *Net, n int) *chattest.Harness {
+	h := chattest.NewWith(t, network)
+	for id := 1; id <= n; id++ {
+		h.Join(id)
+	}
+	return h
+}
+
+func TestPartition(t *testing.T) {
+	network := New(1)
+	h := join(t, network, 3)
+
+	network.Partition(chattest.Host(2))
+	if err := h.Client(1).Publish("during"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+	if err := h.Client(2).Publish("cut off"); status.Code(err) != codes.Unavailable {
+		t.Errorf("publishing from the other side of the partition returned %v, want Unavailable", err)
+	}
+
+	network.Heal()
+	if err := h.Client(1).Publish("after"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+
+	want := map[int]string{
+		1: `"during" from Participant 1; "after" from Participant 1`,
+		2: `"after" from Participant 1`,
+		3: `"during" from Participant 1; "after" from Participant 1`,
+	}
+	for id, received := range want {
+		if got := strings.Join(messages(h, chattest.Host(id)), "; "); got != received {
+			t.Errorf("%s received %s, want %s", chattest.Host(id), got, received)
+		}
+	}
+}
+
+func TestPublishCountsTheAcknowledgedDeliveries(t *testing.T) {
+	network := New(1)
+	h := join(t, network, 3)
+
+	network.Partition(chattest.Host(3))
+	reply, err := h.Server.ParticipantMessages(context.Background(), &proto.ClientInfo{ClientId: 1, Message: "partial", MessageId: "m1"})
+	if err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+	if reply.Delivered != 2 || reply.Recipients != 3 {
+		t.Errorf("delivered to %d/%d participants, want 2/3", reply.Delivered, reply.Recipients)
+	}
+}
+
+func TestDropAndDuplicate(t *testing.T) {
+	network := New(1)
+	h := join(t, network, 3)
+
+	network.SetLink(chattest.ServerAddress, chattest.Host(2), Link{Drop: 1})
+	network.SetLink(chattest.ServerAddress, chattest.Host(3), Link{Duplicate: 1})
+	if err := h.Client(1).Publish("hello"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+
+	if got := messages(h, chattest.Host(1)); len(got) != 1 {
+		t.Errorf("the publisher received %q, want the message once", got)
+	}
+	if got := messages(h, chattest.Host(2)); len(got) != 0 {
+		t.Errorf("%s received %q through a link that drops everything", chattest.Host(2), got)
+	}
+	// the copy arrives, but the participant shows the message once
+	if got := messages(h, chattest.Host(3)); len(got) != 1 || copies(h, chattest.Host(3)) != 1 {
+		t.Errorf("%s received %q and dropped %d copies through a link that duplicates everything, want the message and one copy",
+			chattest.Host(3), got, copies(h, chattest.Host(3)))
+	}
+}
+
+func TestDelay(t *testing.T) {
+	network := New(1)
+	h := join(t, network, 2)
+
+	network.SetLink(chattest.ServerAddress, chattest.Host(2), Link{Delay: 100 * time.Millisecond})
+	start := time.Now()
+	if err := h.Client(1).Publish("slow"); err != nil {
+		t.Fatalf("could not publish: %v", err)
+	}
+	// the server replies once it has delivered to every participant
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("publishing took %v through a link delayed by 100ms", elapsed)
+	}
+	if got := messages(h, chattest.Host(2)); len(got) != 1 {
+		t.Errorf("%s received %q, want the delayed message", chattest.Host(2), got)
+	}
+}
+
+func TestReorder(t *testing.T) {
+	network := New(1)
+	h := join(t, network, 3)
+
+	network.SetLink(chattest.Host(1), chattest.ServerAddress, Link{Reorder: 1, Hold: 300 * time.Millisecond})
+	done := make(chan error)
+	go func() { done <- h.Client(1).DirectMessage(2, "first") }()
+	time.Sleep(50 * time.Millisecond)
+	if err := h.Client(3).DirectMessage(2, "second"); err != nil {
+		t.Fatalf("could not send: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("could not send: %v", err)
+	}
+
+	got := strings.Join(messages(h, chattest.Host(2)), "; ")
+	if want := `"second" from Participant 3; "first" from Participant 1`; got != want {
+		t.Errorf("%s received %s, want %s", chattest.Host(2), got, want)
+	}
+}
+
+func TestSameSeedSameFaults(t *testing.T) {
+	run := func(seed int64) []Fault {
+		network := New(seed)
+		h := join(t, network, 3)
+		network.SetLink(chattest.ServerAddress, chattest.Host(2), Link{Drop: 0.3, Duplicate: 0.3, Reorder: 0.3, Hold: time.Millisecond})
+		for i := 0; i < 20; i++ {
+			h.Client(1).Publish("again")
+		}
+		return network.Faults()
+	}
+
+	first := run(7)
+	if len(first) == 0 {
+		t.Fatal("no fault was injected")
+	}
+	if again := run(7); !reflect.DeepEqual(first, again) {
+		t.Errorf("the same seed injected\n%v\nthen\n%v", first, again)
+	}
+	if other := run(8); reflect.DeepEqual(first, other) {
+		t.Errorf("seeds 7 and 8 injected the same faults %v", first)
+	}
+}
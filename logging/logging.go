@@ -0,0 +1,46 @@
+// Package logging sets up the structured logger shared by the server and the
+// participants and names the fields every service call is logged with, so the
+// logs can be checked against the requirements by a program.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Field names used in the log records.
+const (
+	Event       = "event"       // kind of service call, e.g. join, publish, broadcast, leave
+	Client      = "client"      // id of the participant writing the log
+	Participant = "participant" // id of the participant the event is about
+	Peer        = "peer"        // id of the other participant in a participant to participant call
+	Lamport     = "lamport"     // Lamport time of the event
+	Room        = "room"
+	MessageID   = "message_id"
+)
+
+// Setup installs a text or JSON logger writing to w as the default logger,
+// which the log package then writes through as well. The returned level can
+// be changed while running.
+func Setup(w io.Writer, format, level string) (*slog.LevelVar, error) {
+	var lvl slog.LevelVar
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q, use debug, info, warn or error", level)
+	}
+
+	options := &slog.HandlerOptions{Level: &lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, use text or json", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return &lvl, nil
+}
@@ -0,0 +1,88 @@
+// Package metrics exposes Prometheus metrics over HTTP and records every gRPC
+// call a process handles, for the server and the participants alike.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chitty_grpc_requests_total",
+		Help: "gRPC calls handled, by service, method and status code.",
+	}, []string{"service", "method", "code"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chitty_grpc_request_duration_seconds",
+		Help:    "Time spent handling gRPC calls, by service and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+)
+
+// UnaryServerInterceptor counts and times every unary call handled by the gRPC server.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		service, method := splitMethod(info.FullMethod)
+		grpcRequests.WithLabelValues(service, method, status.Code(err).String()).Inc()
+		grpcDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// splitMethod turns "/proto.CCService/ParticipantJoins" into "proto.CCService" and "ParticipantJoins"
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
+
+// Server serves the metrics over HTTP.
+type Server struct {
+	http     *http.Server
+	listener net.Listener
+}
+
+// Serve listens at addr and exposes the metrics at http://addr/metrics in the
+// background, or returns why addr cannot be listened at.
+func Serve(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not serve metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	s := &Server{http: &http.Server{Handler: mux}, listener: listener}
+	slog.Info("serving metrics", "addr", listener.Addr().String())
+	go func() {
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("could not serve metrics", "addr", addr, "error", err)
+		}
+	}()
+	return s, nil
+}
+
+// Shutdown stops serving the metrics and frees the address, waiting for the
+// scrapes in progress until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.http.Shutdown(ctx)
+	s.listener.Close() // in case Serve has not taken it over yet
+	return err
+}
@@ -0,0 +1,9 @@
+package proto
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI v2 spec of the REST mappings of CCService, generated
+// next to this file by protoc-gen-openapiv2.
+//
+//go:embed proto.swagger.json
+var OpenAPI []byte
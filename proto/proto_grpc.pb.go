@@ -22,6 +22,15 @@ const (
 	CCService_ParticipantMessages_FullMethodName = "/proto.CCService/ParticipantMessages"
 	CCService_ParticipantJoins_FullMethodName    = "/proto.CCService/ParticipantJoins"
 	CCService_ParticipantLeaves_FullMethodName   = "/proto.CCService/ParticipantLeaves"
+	CCService_Participants_FullMethodName        = "/proto.CCService/Participants"
+	CCService_DirectMessage_FullMethodName       = "/proto.CCService/DirectMessage"
+	CCService_SetNickname_FullMethodName         = "/proto.CCService/SetNickname"
+	CCService_JoinRoom_FullMethodName            = "/proto.CCService/JoinRoom"
+	CCService_History_FullMethodName             = "/proto.CCService/History"
+	CCService_Clock_FullMethodName               = "/proto.CCService/Clock"
+	CCService_MessageRead_FullMethodName         = "/proto.CCService/MessageRead"
+	CCService_TakeFloor_FullMethodName           = "/proto.CCService/TakeFloor"
+	CCService_ReleaseFloor_FullMethodName        = "/proto.CCService/ReleaseFloor"
 )
 
 // CCServiceClient is the client API for CCService service.
@@ -31,6 +40,15 @@ type CCServiceClient interface {
 	ParticipantMessages(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
 	ParticipantJoins(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
 	ParticipantLeaves(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	Participants(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ParticipantList, error)
+	DirectMessage(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	SetNickname(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	JoinRoom(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*MessageHistory, error)
+	Clock(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	MessageRead(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	TakeFloor(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	ReleaseFloor(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
 }
 
 type cCServiceClient struct {
@@ -68,6 +86,87 @@ func (c *cCServiceClient) ParticipantLeaves(ctx context.Context, in *ClientInfo,
 	return out, nil
 }
 
+func (c *cCServiceClient) Participants(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ParticipantList, error) {
+	out := new(ParticipantList)
+	err := c.cc.Invoke(ctx, CCService_Participants_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) DirectMessage(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_DirectMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) SetNickname(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_SetNickname_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) JoinRoom(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_JoinRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*MessageHistory, error) {
+	out := new(MessageHistory)
+	err := c.cc.Invoke(ctx, CCService_History_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) Clock(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_Clock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) MessageRead(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_MessageRead_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) TakeFloor(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_TakeFloor_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cCServiceClient) ReleaseFloor(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, CCService_ReleaseFloor_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CCServiceServer is the server API for CCService service.
 // All implementations must embed UnimplementedCCServiceServer
 // for forward compatibility
@@ -75,6 +174,15 @@ type CCServiceServer interface {
 	ParticipantMessages(context.Context, *ClientInfo) (*ServerInfo, error)
 	ParticipantJoins(context.Context, *ClientInfo) (*ServerInfo, error)
 	ParticipantLeaves(context.Context, *ClientInfo) (*ServerInfo, error)
+	Participants(context.Context, *ClientInfo) (*ParticipantList, error)
+	DirectMessage(context.Context, *ClientInfo) (*ServerInfo, error)
+	SetNickname(context.Context, *ClientInfo) (*ServerInfo, error)
+	JoinRoom(context.Context, *ClientInfo) (*ServerInfo, error)
+	History(context.Context, *HistoryRequest) (*MessageHistory, error)
+	Clock(context.Context, *ClientInfo) (*ServerInfo, error)
+	MessageRead(context.Context, *ClientInfo) (*ServerInfo, error)
+	TakeFloor(context.Context, *ClientInfo) (*ServerInfo, error)
+	ReleaseFloor(context.Context, *ClientInfo) (*ServerInfo, error)
 	mustEmbedUnimplementedCCServiceServer()
 }
 
@@ -91,6 +199,33 @@ func (UnimplementedCCServiceServer) ParticipantJoins(context.Context, *ClientInf
 func (UnimplementedCCServiceServer) ParticipantLeaves(context.Context, *ClientInfo) (*ServerInfo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ParticipantLeaves not implemented")
 }
+func (UnimplementedCCServiceServer) Participants(context.Context, *ClientInfo) (*ParticipantList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Participants not implemented")
+}
+func (UnimplementedCCServiceServer) DirectMessage(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DirectMessage not implemented")
+}
+func (UnimplementedCCServiceServer) SetNickname(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNickname not implemented")
+}
+func (UnimplementedCCServiceServer) JoinRoom(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinRoom not implemented")
+}
+func (UnimplementedCCServiceServer) History(context.Context, *HistoryRequest) (*MessageHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedCCServiceServer) Clock(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clock not implemented")
+}
+func (UnimplementedCCServiceServer) MessageRead(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MessageRead not implemented")
+}
+func (UnimplementedCCServiceServer) TakeFloor(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TakeFloor not implemented")
+}
+func (UnimplementedCCServiceServer) ReleaseFloor(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseFloor not implemented")
+}
 func (UnimplementedCCServiceServer) mustEmbedUnimplementedCCServiceServer() {}
 
 // UnsafeCCServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -158,6 +293,168 @@ func _CCService_ParticipantLeaves_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CCService_Participants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).Participants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_Participants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).Participants(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_DirectMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).DirectMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_DirectMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).DirectMessage(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_SetNickname_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).SetNickname(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_SetNickname_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).SetNickname(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_JoinRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).JoinRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_JoinRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).JoinRoom(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_Clock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).Clock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_Clock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).Clock(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_MessageRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).MessageRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_MessageRead_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).MessageRead(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_TakeFloor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).TakeFloor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_TakeFloor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).TakeFloor(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CCService_ReleaseFloor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CCServiceServer).ReleaseFloor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CCService_ReleaseFloor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CCServiceServer).ReleaseFloor(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CCService_ServiceDesc is the grpc.ServiceDesc for CCService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -177,13 +474,57 @@ var CCService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ParticipantLeaves",
 			Handler:    _CCService_ParticipantLeaves_Handler,
 		},
+		{
+			MethodName: "Participants",
+			Handler:    _CCService_Participants_Handler,
+		},
+		{
+			MethodName: "DirectMessage",
+			Handler:    _CCService_DirectMessage_Handler,
+		},
+		{
+			MethodName: "SetNickname",
+			Handler:    _CCService_SetNickname_Handler,
+		},
+		{
+			MethodName: "JoinRoom",
+			Handler:    _CCService_JoinRoom_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _CCService_History_Handler,
+		},
+		{
+			MethodName: "Clock",
+			Handler:    _CCService_Clock_Handler,
+		},
+		{
+			MethodName: "MessageRead",
+			Handler:    _CCService_MessageRead_Handler,
+		},
+		{
+			MethodName: "TakeFloor",
+			Handler:    _CCService_TakeFloor_Handler,
+		},
+		{
+			MethodName: "ReleaseFloor",
+			Handler:    _CCService_ReleaseFloor_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/proto.proto",
 }
 
 const (
-	ParticipantService_ClientJoinReturn_FullMethodName = "/proto.ParticipantService/ClientJoinReturn"
+	ParticipantService_ClientJoinReturn_FullMethodName    = "/proto.ParticipantService/ClientJoinReturn"
+	ParticipantService_ClientMessageReturn_FullMethodName = "/proto.ParticipantService/ClientMessageReturn"
+	ParticipantService_ClientLeaveReturn_FullMethodName   = "/proto.ParticipantService/ClientLeaveReturn"
+	ParticipantService_RequestFloor_FullMethodName        = "/proto.ParticipantService/RequestFloor"
+	ParticipantService_Election_FullMethodName            = "/proto.ParticipantService/Election"
+	ParticipantService_RingElection_FullMethodName        = "/proto.ParticipantService/RingElection"
+	ParticipantService_Coordinator_FullMethodName         = "/proto.ParticipantService/Coordinator"
+	ParticipantService_Heartbeat_FullMethodName           = "/proto.ParticipantService/Heartbeat"
+	ParticipantService_ClientReadReturn_FullMethodName    = "/proto.ParticipantService/ClientReadReturn"
 )
 
 // ParticipantServiceClient is the client API for ParticipantService service.
@@ -191,6 +532,14 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ParticipantServiceClient interface {
 	ClientJoinReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	ClientMessageReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	ClientLeaveReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	RequestFloor(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ClientInfo, error)
+	Election(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ClientInfo, error)
+	RingElection(ctx context.Context, in *ElectionInfo, opts ...grpc.CallOption) (*ClientInfo, error)
+	Coordinator(ctx context.Context, in *ElectionInfo, opts ...grpc.CallOption) (*ClientInfo, error)
+	Heartbeat(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ClientInfo, error)
+	ClientReadReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error)
 }
 
 type participantServiceClient struct {
@@ -210,40 +559,144 @@ func (c *participantServiceClient) ClientJoinReturn(ctx context.Context, in *Cli
 	return out, nil
 }
 
-// ParticipantServiceServer is the server API for ParticipantService service.
-// All implementations must embed UnimplementedParticipantServiceServer
-// for forward compatibility
-type ParticipantServiceServer interface {
-	ClientJoinReturn(context.Context, *ClientInfo) (*ServerInfo, error)
-	mustEmbedUnimplementedParticipantServiceServer()
-}
-
-// UnimplementedParticipantServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedParticipantServiceServer struct {
+func (c *participantServiceClient) ClientMessageReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_ClientMessageReturn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (UnimplementedParticipantServiceServer) ClientJoinReturn(context.Context, *ClientInfo) (*ServerInfo, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ClientJoinReturn not implemented")
+func (c *participantServiceClient) ClientLeaveReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_ClientLeaveReturn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedParticipantServiceServer) mustEmbedUnimplementedParticipantServiceServer() {}
 
-// UnsafeParticipantServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to ParticipantServiceServer will
-// result in compilation errors.
-type UnsafeParticipantServiceServer interface {
-	mustEmbedUnimplementedParticipantServiceServer()
+func (c *participantServiceClient) RequestFloor(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ClientInfo, error) {
+	out := new(ClientInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_RequestFloor_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func RegisterParticipantServiceServer(s grpc.ServiceRegistrar, srv ParticipantServiceServer) {
-	s.RegisterService(&ParticipantService_ServiceDesc, srv)
+func (c *participantServiceClient) Election(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ClientInfo, error) {
+	out := new(ClientInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_Election_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func _ParticipantService_ClientJoinReturn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ClientInfo)
-	if err := dec(in); err != nil {
+func (c *participantServiceClient) RingElection(ctx context.Context, in *ElectionInfo, opts ...grpc.CallOption) (*ClientInfo, error) {
+	out := new(ClientInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_RingElection_FullMethodName, in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
+	return out, nil
+}
+
+func (c *participantServiceClient) Coordinator(ctx context.Context, in *ElectionInfo, opts ...grpc.CallOption) (*ClientInfo, error) {
+	out := new(ClientInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_Coordinator_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *participantServiceClient) Heartbeat(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ClientInfo, error) {
+	out := new(ClientInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_Heartbeat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *participantServiceClient) ClientReadReturn(ctx context.Context, in *ClientInfo, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, ParticipantService_ClientReadReturn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ParticipantServiceServer is the server API for ParticipantService service.
+// All implementations must embed UnimplementedParticipantServiceServer
+// for forward compatibility
+type ParticipantServiceServer interface {
+	ClientJoinReturn(context.Context, *ClientInfo) (*ServerInfo, error)
+	ClientMessageReturn(context.Context, *ClientInfo) (*ServerInfo, error)
+	ClientLeaveReturn(context.Context, *ClientInfo) (*ServerInfo, error)
+	RequestFloor(context.Context, *ClientInfo) (*ClientInfo, error)
+	Election(context.Context, *ClientInfo) (*ClientInfo, error)
+	RingElection(context.Context, *ElectionInfo) (*ClientInfo, error)
+	Coordinator(context.Context, *ElectionInfo) (*ClientInfo, error)
+	Heartbeat(context.Context, *ClientInfo) (*ClientInfo, error)
+	ClientReadReturn(context.Context, *ClientInfo) (*ServerInfo, error)
+	mustEmbedUnimplementedParticipantServiceServer()
+}
+
+// UnimplementedParticipantServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedParticipantServiceServer struct {
+}
+
+func (UnimplementedParticipantServiceServer) ClientJoinReturn(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClientJoinReturn not implemented")
+}
+func (UnimplementedParticipantServiceServer) ClientMessageReturn(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClientMessageReturn not implemented")
+}
+func (UnimplementedParticipantServiceServer) ClientLeaveReturn(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClientLeaveReturn not implemented")
+}
+func (UnimplementedParticipantServiceServer) RequestFloor(context.Context, *ClientInfo) (*ClientInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestFloor not implemented")
+}
+func (UnimplementedParticipantServiceServer) Election(context.Context, *ClientInfo) (*ClientInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Election not implemented")
+}
+func (UnimplementedParticipantServiceServer) RingElection(context.Context, *ElectionInfo) (*ClientInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RingElection not implemented")
+}
+func (UnimplementedParticipantServiceServer) Coordinator(context.Context, *ElectionInfo) (*ClientInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Coordinator not implemented")
+}
+func (UnimplementedParticipantServiceServer) Heartbeat(context.Context, *ClientInfo) (*ClientInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedParticipantServiceServer) ClientReadReturn(context.Context, *ClientInfo) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClientReadReturn not implemented")
+}
+func (UnimplementedParticipantServiceServer) mustEmbedUnimplementedParticipantServiceServer() {}
+
+// UnsafeParticipantServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ParticipantServiceServer will
+// result in compilation errors.
+type UnsafeParticipantServiceServer interface {
+	mustEmbedUnimplementedParticipantServiceServer()
+}
+
+func RegisterParticipantServiceServer(s grpc.ServiceRegistrar, srv ParticipantServiceServer) {
+	s.RegisterService(&ParticipantService_ServiceDesc, srv)
+}
+
+func _ParticipantService_ClientJoinReturn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
 		return srv.(ParticipantServiceServer).ClientJoinReturn(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
@@ -256,6 +709,150 @@ func _ParticipantService_ClientJoinReturn_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ParticipantService_ClientMessageReturn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).ClientMessageReturn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_ClientMessageReturn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).ClientMessageReturn(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_ClientLeaveReturn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).ClientLeaveReturn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_ClientLeaveReturn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).ClientLeaveReturn(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_RequestFloor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).RequestFloor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_RequestFloor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).RequestFloor(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_Election_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).Election(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_Election_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).Election(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_RingElection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ElectionInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).RingElection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_RingElection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).RingElection(ctx, req.(*ElectionInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_Coordinator_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ElectionInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).Coordinator(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_Coordinator_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).Coordinator(ctx, req.(*ElectionInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).Heartbeat(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParticipantService_ClientReadReturn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServiceServer).ClientReadReturn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParticipantService_ClientReadReturn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServiceServer).ClientReadReturn(ctx, req.(*ClientInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ParticipantService_ServiceDesc is the grpc.ServiceDesc for ParticipantService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -267,6 +864,313 @@ var ParticipantService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ClientJoinReturn",
 			Handler:    _ParticipantService_ClientJoinReturn_Handler,
 		},
+		{
+			MethodName: "ClientMessageReturn",
+			Handler:    _ParticipantService_ClientMessageReturn_Handler,
+		},
+		{
+			MethodName: "ClientLeaveReturn",
+			Handler:    _ParticipantService_ClientLeaveReturn_Handler,
+		},
+		{
+			MethodName: "RequestFloor",
+			Handler:    _ParticipantService_RequestFloor_Handler,
+		},
+		{
+			MethodName: "Election",
+			Handler:    _ParticipantService_Election_Handler,
+		},
+		{
+			MethodName: "RingElection",
+			Handler:    _ParticipantService_RingElection_Handler,
+		},
+		{
+			MethodName: "Coordinator",
+			Handler:    _ParticipantService_Coordinator_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _ParticipantService_Heartbeat_Handler,
+		},
+		{
+			MethodName: "ClientReadReturn",
+			Handler:    _ParticipantService_ClientReadReturn_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/proto.proto",
+}
+
+const (
+	AdminService_ListParticipants_FullMethodName = "/proto.AdminService/ListParticipants"
+	AdminService_KickParticipant_FullMethodName  = "/proto.AdminService/KickParticipant"
+	AdminService_Broadcast_FullMethodName        = "/proto.AdminService/Broadcast"
+	AdminService_GetClock_FullMethodName         = "/proto.AdminService/GetClock"
+	AdminService_DumpState_FullMethodName        = "/proto.AdminService/DumpState"
+	AdminService_SetLogLevel_FullMethodName      = "/proto.AdminService/SetLogLevel"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	ListParticipants(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ParticipantList, error)
+	KickParticipant(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error)
+	Broadcast(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error)
+	GetClock(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error)
+	DumpState(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerState, error)
+	SetLogLevel(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) ListParticipants(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ParticipantList, error) {
+	out := new(ParticipantList)
+	err := c.cc.Invoke(ctx, AdminService_ListParticipants_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) KickParticipant(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, AdminService_KickParticipant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Broadcast(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, AdminService_Broadcast_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetClock(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, AdminService_GetClock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DumpState(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerState, error) {
+	out := new(ServerState)
+	err := c.cc.Invoke(ctx, AdminService_DumpState_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, in *AdminRequest, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, AdminService_SetLogLevel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility
+type AdminServiceServer interface {
+	ListParticipants(context.Context, *AdminRequest) (*ParticipantList, error)
+	KickParticipant(context.Context, *AdminRequest) (*ServerInfo, error)
+	Broadcast(context.Context, *AdminRequest) (*ServerInfo, error)
+	GetClock(context.Context, *AdminRequest) (*ServerInfo, error)
+	DumpState(context.Context, *AdminRequest) (*ServerState, error)
+	SetLogLevel(context.Context, *AdminRequest) (*ServerInfo, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServiceServer struct {
+}
+
+func (UnimplementedAdminServiceServer) ListParticipants(context.Context, *AdminRequest) (*ParticipantList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListParticipants not implemented")
+}
+func (UnimplementedAdminServiceServer) KickParticipant(context.Context, *AdminRequest) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KickParticipant not implemented")
+}
+func (UnimplementedAdminServiceServer) Broadcast(context.Context, *AdminRequest) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Broadcast not implemented")
+}
+func (UnimplementedAdminServiceServer) GetClock(context.Context, *AdminRequest) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClock not implemented")
+}
+func (UnimplementedAdminServiceServer) DumpState(context.Context, *AdminRequest) (*ServerState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpState not implemented")
+}
+func (UnimplementedAdminServiceServer) SetLogLevel(context.Context, *AdminRequest) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_ListParticipants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListParticipants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ListParticipants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListParticipants(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_KickParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).KickParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_KickParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).KickParticipant(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Broadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Broadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Broadcast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Broadcast(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetClock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetClock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetClock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetClock(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DumpState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DumpState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DumpState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DumpState(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, req.(*AdminRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListParticipants",
+			Handler:    _AdminService_ListParticipants_Handler,
+		},
+		{
+			MethodName: "KickParticipant",
+			Handler:    _AdminService_KickParticipant_Handler,
+		},
+		{
+			MethodName: "Broadcast",
+			Handler:    _AdminService_Broadcast_Handler,
+		},
+		{
+			MethodName: "GetClock",
+			Handler:    _AdminService_GetClock_Handler,
+		},
+		{
+			MethodName: "DumpState",
+			Handler:    _AdminService_DumpState_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _AdminService_SetLogLevel_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/proto.proto",
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+)
+
+// Admin implements the AdminService, which lets operators inspect and manage a running server.
+type Admin struct {
+	proto.UnimplementedAdminServiceServer // Necessary
+	server                                *Server
+	logLevel                              *slog.LevelVar
+}
+
+// adminAuth rejects AdminService calls that do not carry "authorization: Bearer <token>".
+// Calls to the other services are let through.
+func adminAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, "/proto.AdminService/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte("Bearer "+token)) != 1 {
+			slog.Warn("rejected admin call", logging.Event, "admin", "method", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "missing or wrong admin token")
+		}
+		slog.Info("admin call", logging.Event, "admin", "method", info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+// when an operator lists the participants
+func (a *Admin) ListParticipants(ctx context.Context, in *proto.AdminRequest) (*proto.ParticipantList, error) {
+	return &proto.ParticipantList{
+		Participants: a.server.participantList(),
+		LamportTime:  int64(a.server.clock()),
+	}, nil
+}
+
+// when an operator removes a participant from the chat
+func (a *Admin) KickParticipant(ctx context.Context, in *proto.AdminRequest) (*proto.ServerInfo, error) {
+	if !a.server.removeParticipant(ctx, in.ClientId, true) {
+		return nil, status.Errorf(codes.NotFound, "participant %d is not in the chat", in.ClientId)
+	}
+	return &proto.ServerInfo{
+		ServerName:  a.server.name,
+		LamportTime: int64(a.server.clock()),
+	}, nil
+}
+
+// when an operator sends a system announcement to every participant; the
+// reply counts them and those that acknowledged it, like a publish's
+func (a *Admin) Broadcast(ctx context.Context, in *proto.AdminRequest) (*proto.ServerInfo, error) {
+	if !validMessage(in.Message) {
+		return nil, status.Error(codes.InvalidArgument, "send an announcement of UTF-8 and within 128 characters in length")
+	}
+
+	lamportTime := a.server.local("announcement", fmt.Sprintf("Operator announces %q", in.Message))
+	slog.Info("operator announces message", logging.Event, "announcement", logging.Lamport, lamportTime, "message", in.Message)
+
+	announcement := &proto.ClientInfo{
+		ClientId:     -1,
+		Message:      in.Message,
+		Announcement: true,
+	}
+	recipients := a.server.participantList()
+	delivered := 0
+	for _, participant := range recipients {
+		if a.server.deliverMessage(ctx, announcement, participant) == nil {
+			delivered++
+		}
+	}
+	slog.Info("announcement delivered", logging.Event, "delivered", "delivered", delivered, "recipients", len(recipients), logging.Lamport, a.server.clock())
+
+	return &proto.ServerInfo{
+		ServerName:  a.server.name,
+		LamportTime: int64(a.server.clock()),
+		Delivered:   int64(delivered),
+		Recipients:  int64(len(recipients)),
+	}, nil
+}
+
+// when an operator asks for the server's clocks
+func (a *Admin) GetClock(ctx context.Context, in *proto.AdminRequest) (*proto.ServerInfo, error) {
+	return &proto.ServerInfo{
+		ServerName:  a.server.name,
+		LamportTime: int64(a.server.clock()),
+		VectorClock: a.server.events.Clock(),
+	}, nil
+}
+
+// when an operator asks for everything the server knows
+func (a *Admin) DumpState(ctx context.Context, in *proto.AdminRequest) (*proto.ServerState, error) {
+	return &proto.ServerState{
+		ServerName:   a.server.name,
+		LamportTime:  int64(a.server.clock()),
+		VectorClock:  a.server.events.Clock(),
+		Participants: a.server.participantList(),
+		LogLevel:     a.logLevel.Level().String(),
+	}, nil
+}
+
+// when an operator changes how much the server logs
+func (a *Admin) SetLogLevel(ctx context.Context, in *proto.AdminRequest) (*proto.ServerInfo, error) {
+	if err := a.logLevel.UnmarshalText([]byte(in.LogLevel)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown log level %q, use debug, info, warn or error", in.LogLevel)
+	}
+	slog.Warn("log level changed", logging.Event, "admin", "level", a.logLevel.Level().String())
+
+	return &proto.ServerInfo{
+		ServerName:  a.server.name,
+		LamportTime: int64(a.server.clock()),
+	}, nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+// The participants in mutex mode agree on the floor among themselves with
+// Ricart-Agrawala and tell the server who holds it. While it is held, the
+// server accepts publishes from the holder only, whether the others are in
+// mutex mode, plain clients or REST callers.
+
+// when participant got the floor from every other participant
+func (s *Server) TakeFloor(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("floor-take", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d takes the floor", in.ClientId))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(in.ClientId) == nil {
+		return nil, status.Errorf(codes.NotFound, "participant %d is not in the chat", in.ClientId)
+	}
+	if s.floorHolder != 0 && s.floorHolder != in.ClientId {
+		return nil, status.Errorf(codes.FailedPrecondition, "participant %d holds the floor", s.floorHolder)
+	}
+	s.floorHolder = in.ClientId
+	slog.Info("participant takes the floor", logging.Event, "floor-take", logging.Participant, in.ClientId, logging.Lamport, lamportTime)
+
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+	}, nil
+}
+
+// when participant gives up the floor
+func (s *Server) ReleaseFloor(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("floor-release", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d releases the floor", in.ClientId))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.floorHolder != in.ClientId {
+		return nil, status.Errorf(codes.FailedPrecondition, "participant %d does not hold the floor", in.ClientId)
+	}
+	s.floorHolder = 0
+	slog.Info("participant releases the floor", logging.Event, "floor-release", logging.Participant, in.ClientId, logging.Lamport, lamportTime)
+
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+	}, nil
+}
+
+// checkFloor rejects a publish while another participant holds the floor
+func (s *Server) checkFloor(clientID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.floorHolder != 0 && s.floorHolder != clientID {
+		return status.Errorf(codes.FailedPrecondition, "participant %d holds the floor", s.floorHolder)
+	}
+	return nil
+}
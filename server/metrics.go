@@ -0,0 +1,61 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"strconv"
+	"time"
+)
+
+// Prometheus metrics of the server, served when it is started with -metrics
+
+var (
+	joins = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_joins_total",
+		Help: "Participants that joined the chat.",
+	})
+	leaves = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_leaves_total",
+		Help: "Participants that left the chat.",
+	})
+	publishes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_publishes_total",
+		Help: "Messages published by participants.",
+	})
+	duplicatePublishes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chitty_duplicate_publishes_total",
+		Help: "Retried publishes dropped because the server had already broadcast the message.",
+	})
+	failedDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chitty_failed_deliveries_total",
+		Help: "Broadcasts that could not be delivered, by participant.",
+	}, []string{"participant"})
+	deliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chitty_broadcast_delivery_seconds",
+		Help:    "Time to deliver a broadcast to one participant during fan-out, by participant.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"participant"})
+)
+
+// registerClock exports the server's Lamport time as a gauge. With several
+// servers in one process only the first one started with metrics is exported:
+// the gauge is returned to be unregistered on Stop, or nil for the others.
+func registerClock(s *Server) prometheus.Collector {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "chitty_lamport_time",
+		Help: "Current Lamport time of the server.",
+	}, func() float64 { return float64(s.clock()) })
+	if err := prometheus.Register(gauge); err != nil {
+		return nil // another one is exported already
+	}
+	return gauge
+}
+
+// observeDelivery records how one broadcast to a participant went
+func observeDelivery(clientID int64, start time.Time, err error) {
+	participant := strconv.FormatInt(clientID, 10)
+	deliveryLatency.WithLabelValues(participant).Observe(time.Since(start).Seconds())
+	if err != nil {
+		failedDeliveries.WithLabelValues(participant).Inc()
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+// Participants started with -read-receipts tell the server when they have shown
+// a published message; the server passes the receipt on to the publisher.
+
+// when participant has read a message of another participant
+func (s *Server) MessageRead(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("read", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d read message %s of Participant %d", in.ClientId, in.MessageId, in.RecipientId))
+	slog.Info("participant read message", logging.Event, "read", logging.Participant, in.ClientId, "publisher", in.RecipientId, logging.MessageID, in.MessageId, logging.Lamport, lamportTime)
+
+	if in.MessageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "a read receipt needs the id of the message")
+	}
+	s.mu.Lock()
+	publisher := s.find(in.RecipientId)
+	if reader := s.find(in.ClientId); reader != nil {
+		in.Nickname = reader.Nickname
+	}
+	s.mu.Unlock()
+	if publisher == nil {
+		return nil, status.Errorf(codes.NotFound, "participant %d is not in the chat", in.RecipientId)
+	}
+
+	publisherClient, err := s.connectToClient(publisher)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "could not reach participant %d: %v", in.RecipientId, err)
+	}
+	lamportTime, vectorClock := s.send("read-receipt", participantHost(publisher.ClientId),
+		fmt.Sprintf("%s tells Participant %d that Participant %d read message %s", s.name, publisher.ClientId, in.ClientId, in.MessageId))
+
+	deliveryCtx, cancel := context.WithTimeout(ctx, s.opts.Timeouts.Delivery)
+	defer cancel()
+	start := time.Now()
+	_, err = publisherClient.ClientReadReturn(deliveryCtx, &proto.ClientInfo{
+		ClientId:    in.ClientId,
+		LamportTime: int64(lamportTime),
+		Message:     in.Message,
+		RecipientId: in.RecipientId,
+		Nickname:    in.Nickname,
+		MessageId:   in.MessageId,
+		VectorClock: vectorClock,
+	})
+	observeDelivery(publisher.ClientId, start, err)
+	if err != nil {
+		slog.Warn("could not deliver read receipt", logging.Event, "read-receipt", logging.Participant, in.ClientId, "recipient", publisher.ClientId, "error", err)
+		return nil, status.Errorf(codes.Unavailable, "could not deliver to participant %d: %v", in.RecipientId, err)
+	}
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+	}, nil
+}
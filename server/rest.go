@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"github.com/Tien197/Chitty-Chat/tracing"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// serveREST serves the HTTP mappings of CCService (see proto/proto.proto) at
+// the configured address. The gateway calls the gRPC server like any other
+// client, so the REST calls are logged, counted and traced the same way.
+func (s *Server) serveREST() error {
+	address, ok := s.listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("the REST gateway needs a TCP listener, not %s", s.listener.Addr().Network())
+	}
+	// The gateway dials this very server, whose certificate need not name localhost
+	creds := insecure.NewCredentials()
+	if s.opts.TLS.Cert != "" {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	gateway := runtime.NewServeMux()
+	err := proto.RegisterCCServiceHandlerFromEndpoint(context.Background(), gateway, "localhost:"+strconv.Itoa(address.Port),
+		[]grpc.DialOption{grpc.WithTransportCredentials(creds), tracing.DialOption()})
+	if err != nil {
+		return fmt.Errorf("could not create the REST gateway: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", gateway)
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(proto.OpenAPI)
+	})
+
+	listener, err := net.Listen("tcp", s.opts.HTTP)
+	if err != nil {
+		return fmt.Errorf("could not create the REST gateway: %w", err)
+	}
+	s.rest = &http.Server{Handler: mux}
+	s.restListener = listener
+	slog.Info("REST gateway started", logging.Event, "start", "address", listener.Addr().String())
+	go func() {
+		if err := s.rest.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("could not serve the REST gateway", "error", err)
+		}
+	}()
+	return nil
+}
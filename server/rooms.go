@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// Participants start in the lobby and can move to another room with /join;
+// a published message only reaches the participants in the publisher's room.
+// The server keeps the last historySize messages of every room for /history.
+
+const (
+	lobby       = "lobby"
+	historySize = 100
+)
+
+// when participant picks a nickname, which is shown next to its messages
+func (s *Server) SetNickname(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("nick", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d is now called %q", in.ClientId, in.Nickname))
+
+	nickname := strings.TrimSpace(in.Nickname)
+	if nickname == "" || !utf8.ValidString(nickname) || utf8.RuneCountInString(nickname) > 32 {
+		return nil, status.Error(codes.InvalidArgument, "a nickname has between 1 and 32 UTF-8 characters")
+	}
+	if err := s.update(in.ClientId, func(participant *proto.ClientInfo) { participant.Nickname = nickname }); err != nil {
+		return nil, err
+	}
+	slog.Info("participant sets nickname", logging.Event, "nick", logging.Participant, in.ClientId, "nickname", nickname, logging.Lamport, lamportTime)
+
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+	}, nil
+}
+
+// when participant moves to another room
+func (s *Server) JoinRoom(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("room", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d moves to room %q", in.ClientId, in.Room))
+
+	room, err := checkRoom(in.Room)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.update(in.ClientId, func(participant *proto.ClientInfo) { participant.Room = room }); err != nil {
+		return nil, err
+	}
+	slog.Info("participant joins room", logging.Event, "room", logging.Participant, in.ClientId, logging.Room, room, logging.Lamport, lamportTime)
+
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+	}, nil
+}
+
+// checkRoom returns the trimmed room name, if it is a single word of at most 32 characters
+func checkRoom(name string) (string, error) {
+	room := strings.TrimSpace(name)
+	if room == "" || strings.ContainsAny(room, " \t") || utf8.RuneCountInString(room) > 32 {
+		return "", status.Error(codes.InvalidArgument, "a room name is a single word of at most 32 characters")
+	}
+	return room, nil
+}
+
+// when participant asks for the latest messages of its room
+func (s *Server) History(ctx context.Context, in *proto.HistoryRequest) (*proto.MessageHistory, error) {
+	lamportTime := s.received("history", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d asks for %d messages", in.ClientId, in.Count))
+
+	if in.Count <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "the number of messages must be positive")
+	}
+
+	s.mu.Lock()
+	participant := s.find(in.ClientId)
+	var messages []*proto.ClientInfo
+	if participant != nil {
+		messages = s.history[participant.Room]
+		if int64(len(messages)) > in.Count {
+			messages = messages[int64(len(messages))-in.Count:]
+		}
+		messages = append([]*proto.ClientInfo(nil), messages...)
+	}
+	s.mu.Unlock()
+	if participant == nil {
+		return nil, status.Errorf(codes.NotFound, "participant %d is not in the chat", in.ClientId)
+	}
+
+	lamportTime, vectorClock := s.send("history-reply", participantHost(in.ClientId), fmt.Sprintf("%s sends %d messages", s.name, len(messages)))
+	return &proto.MessageHistory{
+		Messages:    messages,
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
+
+// when participant asks for the server's clocks
+func (s *Server) Clock(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("clock", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d asks for the clock", in.ClientId))
+
+	lamportTime, vectorClock := s.send("clock-reply", participantHost(in.ClientId), fmt.Sprintf("%s sends its clock", s.name))
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(lamportTime),
+		VectorClock: vectorClock,
+	}, nil
+}
+
+// record adds a published message to the history of the publisher's room and
+// returns it with the publisher's nickname and room filled in
+func (s *Server) record(in *proto.ClientInfo, lamportTime int) *proto.ClientInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message := &proto.ClientInfo{
+		ClientId:    in.ClientId,
+		LamportTime: int64(lamportTime),
+		Message:     in.Message,
+		Room:        lobby,
+		MessageId:   in.MessageId,
+	}
+	if publisher := s.find(in.ClientId); publisher != nil {
+		message.Nickname = publisher.Nickname
+		message.Room = publisher.Room
+	}
+
+	history := append(s.history[message.Room], message)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	s.history[message.Room] = history
+	if err := s.store.append(message); err != nil {
+		slog.Warn("could not keep the message in the data directory", logging.Event, "publish", logging.Participant, in.ClientId, "error", err)
+	}
+	return message
+}
+
+// inRoom returns the participants in room
+func (s *Server) inRoom(room string) []*proto.ClientInfo {
+	var participants []*proto.ClientInfo
+	for _, participant := range s.participantList() {
+		if participant.Room == room {
+			participants = append(participants, participant)
+		}
+	}
+	return participants
+}
+
+// update changes the participant with id clientID under s.mu
+func (s *Server) update(clientID int64, change func(participant *proto.ClientInfo)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	participant := s.find(clientID)
+	if participant == nil {
+		return status.Errorf(codes.NotFound, "participant %d is not in the chat", clientID)
+	}
+	change(participant)
+	return nil
+}
+
+// find must be called with s.mu held
+func (s *Server) find(clientID int64) *proto.ClientInfo {
+	for _, participant := range s.participants {
+		if participant.ClientId == clientID {
+			return participant
+		}
+	}
+	return nil
+}
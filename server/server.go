@@ -1,128 +1,704 @@
-package main
+// Package server implements the Chitty-Chat server: the CCService the
+// participants join, publish and leave through, which broadcasts every event
+// to the participants' ParticipantService with the server's Lamport time, and
+// the optional AdminService and REST gateway. cmd/chitty-server runs it from
+// the command line; other programs and tests start one with New and Start.
+package server
 
 import (
 	"context"
-	"flag"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/dedup"
+	"github.com/Tien197/Chitty-Chat/eventlog"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"github.com/Tien197/Chitty-Chat/metrics"
 	"github.com/Tien197/Chitty-Chat/proto"
+	"github.com/Tien197/Chitty-Chat/tracing"
+	"github.com/Tien197/Chitty-Chat/webhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
-	"log"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"log/slog"
 	"net"
-	"os"
-	"os/signal"
+	"net/http"
 	"strconv"
-	"syscall"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
-// Struct that will be used to represent the Server.
+// Options configure a Server: the settings of the config file, and what a
+// program embedding the server can hand it instead of a port, e.g. an
+// in-memory listener and dialer in tests.
+type Options struct {
+	config.Server
+	Listener    net.Listener      // serve on this listener instead of Port
+	DialOptions []grpc.DialOption // added to the options the participants are dialed with
+	LogLevel    *slog.LevelVar    // changed by the AdminService's SetLogLevel; a new one when nil
+}
+
+// Server is the Chitty-Chat server.
 type Server struct {
 	proto.UnimplementedCCServiceServer // Necessary
 	name                               string
-	port                               int
+	opts                               Options
 	lamportTime                        int
-	participants                       []int
+	participants                       []*proto.ClientInfo
+	clients                            map[int64]*grpc.ClientConn     // connections to the participants, by client id
+	history                            map[string][]*proto.ClientInfo // recent messages by room
+	store                              *store                         // keeps history in the data directory; nil without one
+	published                          map[int64]*dedup.Window        // ids of the recent messages, by publisher
+	floorHolder                        int64                          // client id of the participant holding the floor, 0 when free
+	mu                                 sync.Mutex                     // guards participants, clients, history, published and floorHolder
+	clockMu                            sync.Mutex                     // guards lamportTime
+	events                             *eventlog.Logger
+	health                             *health.Server
+	webhooks                           *webhook.Dispatcher
+	grpcServer                         *grpc.Server
+	listener                           net.Listener
+	rest                               *http.Server // nil unless serving the REST gateway
+	restListener                       net.Listener
+	metrics                            *metrics.Server // nil unless serving metrics
+	clockGauge                         prometheus.Collector
 }
 
-// Used to get the user-defined port for the server from the command line
-var port = flag.Int("port", 0, "server port number")
+// New returns a server that is not serving yet. The event log and the
+// webhook dead-letter file are opened here, and the history kept in the data
+// directory is loaded.
+func New(opts Options) (*Server, error) {
+	if opts.Timeouts.Delivery == 0 {
+		opts.Timeouts.Delivery = 5 * time.Second
+	}
+	if opts.LogLevel == nil {
+		opts.LogLevel = new(slog.LevelVar)
+	}
 
-func main() {
-	// Get the port from the command line when the server is run
-	flag.Parse()
+	events, err := eventlog.Open("server", opts.EventLog)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the event log: %w", err)
+	}
+	webhooks, err := webhook.Open(opts.Webhooks.Hooks, opts.Webhooks.Retries, opts.Webhooks.DeadLetter)
+	if err != nil {
+		events.Close()
+		return nil, fmt.Errorf("could not open the webhook dead-letter file: %w", err)
+	}
+	store, history, lamportTime, err := openStore(opts.DataDir)
+	if err != nil {
+		webhooks.Close(context.Background())
+		events.Close()
+		return nil, fmt.Errorf("could not load the history: %w", err)
+	}
 
-	// Create a server struct
-	server := &Server{
+	return &Server{
 		name:         "Chitty-Chat",
-		port:         *port,
-		lamportTime:  1,
-		participants: make([]int, 0),
+		opts:         opts,
+		lamportTime:  max(1, lamportTime), // later than the kept messages
+		participants: make([]*proto.ClientInfo, 0),
+		clients:      make(map[int64]*grpc.ClientConn),
+		history:      history,
+		store:        store,
+		published:    make(map[int64]*dedup.Window),
+		events:       events,
+		health:       health.NewServer(),
+		webhooks:     webhooks,
+	}, nil
+}
+
+// Start listens at the configured port (or serves the configured listener) and
+// serves the services in the background. If it fails, it closes what New
+// opened, as Stop would.
+func (s *Server) Start() error {
+	if err := s.start(); err != nil {
+		if closeErr := s.close(); closeErr != nil {
+			slog.Warn("could not close the server after a failed start", "error", closeErr)
+		}
+		return err
 	}
+	return nil
+}
 
-	// Start the server
-	go startServer(server)
+func (s *Server) start() error {
+	creds, err := s.opts.TLS.Credentials()
+	if err != nil {
+		return fmt.Errorf("could not load the TLS certificate: %w", err)
+	}
+	s.grpcServer = grpc.NewServer(grpc.Creds(creds), grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor(), adminAuth(s.opts.AdminToken)), tracing.ServerOption())
 
-	// Keep the server running until it is manually quit
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT)
+	s.health.SetServingStatus(proto.CCService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	s.listener = s.opts.Listener
+	if s.listener == nil {
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(s.opts.Port))
+		if err != nil {
+			return fmt.Errorf("could not create the server: %w", err)
+		}
+		s.listener = listener
+	}
+	slog.Info("server started", logging.Event, "start", "server", s.name, "address", s.listener.Addr().String(), logging.Lamport, s.clock())
+	s.events.LocalEvent("start", s.clock(), fmt.Sprintf("Started %s at %s", s.name, s.listener.Addr()))
 
-	// Block until a signal is received
-	<-sigChan
+	// Register the grpc server and serve its listener
+	proto.RegisterCCServiceServer(s.grpcServer, s)
+	if s.opts.AdminToken != "" {
+		proto.RegisterAdminServiceServer(s.grpcServer, &Admin{server: s, logLevel: s.opts.LogLevel})
+	}
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+	if s.opts.Reflection {
+		reflection.Register(s.grpcServer)
+	}
 
-	log.Printf("%s was shut down", server.name)
-}
+	if s.opts.Metrics != "" {
+		metricsServer, err := metrics.Serve(s.opts.Metrics)
+		if err != nil {
+			s.listener.Close()
+			return err
+		}
+		s.metrics = metricsServer
+		s.clockGauge = registerClock(s)
+	}
+	if s.opts.HTTP != "" {
+		if err := s.serveREST(); err != nil {
+			s.stopMetrics()
+			s.listener.Close()
+			return err
+		}
+	}
 
-func startServer(server *Server) {
+	// The server as a whole ("") answers as soon as it runs (liveness); CCService
+	// is ready once the listener is up. The kept history was loaded by New, so
+	// nothing else holds readiness back.
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(proto.CCService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
 
-	// Create a new grpc server
-	grpcServer := grpc.NewServer()
+	go func() {
+		if err := s.grpcServer.Serve(s.listener); err != nil {
+			slog.Error("could not serve listener", "error", err)
+		}
+	}()
+	return nil
+}
 
-	// Make the server listen at the given port (convert int port to string)
+// Addr returns the address the server listens at, once started.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
 
-	listener, err := net.Listen("tcp", ":"+strconv.Itoa(server.port))
+// RESTAddr returns the address of the REST gateway, or nil unless it is served.
+func (s *Server) RESTAddr() net.Addr {
+	if s.restListener == nil {
+		return nil
+	}
+	return s.restListener.Addr()
+}
 
-	if err != nil {
-		log.Fatalf("Could not create the server %v", err)
+// Stop reports the server as not serving, finishes the calls in progress,
+// delivers the queued webhook events within the delivery timeout and closes
+// the connections, the metrics and the event log.
+func (s *Server) Stop() error {
+	s.health.Shutdown()
+	var errs []error
+	if s.rest != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeouts.Delivery)
+		errs = append(errs, s.rest.Shutdown(ctx))
+		cancel()
+		s.restListener.Close() // in case Serve has not taken it over yet
+	}
+	errs = append(errs, s.stopMetrics())
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
 	}
-	log.Printf("Started %s at port: %d at Lamport time %d \n", server.name, server.port, server.lamportTime)
 
-	// Register the grpc server and serve its listener
-	proto.RegisterCCServiceServer(grpcServer, server)
+	s.mu.Lock()
+	for id, conn := range s.clients {
+		errs = append(errs, conn.Close())
+		delete(s.clients, id)
+	}
+	s.mu.Unlock()
 
-	serveError := grpcServer.Serve(listener)
-	if serveError != nil {
-		log.Fatalf("Could not serve listener")
+	errs = append(errs, s.close())
+	slog.Info("server shut down", logging.Event, "shutdown", logging.Lamport, s.clock())
+	return errors.Join(errs...)
+}
+
+// close delivers the queued webhook events within the delivery timeout and
+// closes the dead-letter file, the history file and the event log, which New opened
+func (s *Server) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeouts.Delivery)
+	defer cancel()
+	return errors.Join(s.webhooks.Close(ctx), s.store.close(), s.events.Close())
+}
+
+// stopMetrics stops serving the metrics, if they are served
+func (s *Server) stopMetrics() error {
+	if s.metrics == nil {
+		return nil
 	}
+	if s.clockGauge != nil {
+		prometheus.Unregister(s.clockGauge)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeouts.Delivery)
+	defer cancel()
+	return s.metrics.Shutdown(ctx)
 }
 
 // when participant sends message
 func (s *Server) ParticipantMessages(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
-	log.Printf("Participant %d sends message: \"%s\" at Lamport time ... \n", in.ClientId, in.Message)
+	lamportTime := s.received("publish", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d sends message %q", in.ClientId, in.Message))
+	slog.Info("participant publishes message", logging.Event, "publish", logging.Participant, in.ClientId, logging.Lamport, lamportTime, "message", in.Message)
+	if err := s.checkMessage(in); err != nil {
+		return nil, err
+	}
+	if err := s.checkFloor(in.ClientId); err != nil {
+		return nil, err
+	}
+
+	// a publish retried after a timeout that did get through the first time is
+	// answered with the counts of the first broadcast, once it is over
+	result, duplicate := s.delivery(in)
+	if duplicate {
+		slog.Info("dropped duplicate publish", logging.Event, "publish", logging.Participant, in.ClientId, logging.MessageID, in.MessageId, logging.Lamport, lamportTime)
+		duplicatePublishes.Inc()
+		select {
+		case <-result.done:
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return &proto.ServerInfo{
+			ServerName:  s.name,
+			LamportTime: int64(s.clock()),
+			Delivered:   result.delivered,
+			Recipients:  result.recipients,
+		}, nil
+	}
+	publishes.Inc()
+
+	// broadcast the message to every participant in the room, including the one that published it
+	message := s.record(in, lamportTime)
+	s.webhooks.Send(webhook.Event{
+		Type:        webhook.Message,
+		ClientID:    message.ClientId,
+		Nickname:    message.Nickname,
+		Room:        message.Room,
+		Message:     message.Message,
+		LamportTime: message.LamportTime,
+	})
+	// each participant acknowledges the broadcast by replying to it. The
+	// broadcast goes on when the publisher gives up waiting: its retry is
+	// answered with the outcome, so it has to reach everyone.
+	broadcastCtx := context.WithoutCancel(ctx)
+	recipients := s.inRoom(message.Room)
+	delivered := 0
+	for _, participant := range recipients {
+		if s.deliverMessage(broadcastCtx, message, participant) == nil {
+			delivered++
+		}
+	}
+	slog.Info("message delivered", logging.Event, "delivered", logging.Participant, in.ClientId, logging.MessageID, in.MessageId, "delivered", delivered, "recipients", len(recipients), logging.Lamport, s.clock())
+	result.delivered, result.recipients = int64(delivered), int64(len(recipients))
+	close(result.done)
+
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+		Delivered:   result.delivered,
+		Recipients:  result.recipients,
+	}, nil
+}
+
+// validMessage reports whether text is 1 to 128 characters of UTF-8, the limit
+// the clients check as well
+func validMessage(text string) bool {
+	return text != "" && utf8.ValidString(text) && utf8.RuneCountInString(text) <= 128
+}
+
+// checkMessage rejects a message that fails validMessage, or whose sender is
+// not in the chat. REST callers reach the server without going through a client.
+func (s *Server) checkMessage(in *proto.ClientInfo) error {
+	if !validMessage(in.Message) {
+		return status.Error(grpccodes.InvalidArgument, "send a message of UTF-8 and within 128 characters in length")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(in.ClientId) == nil {
+		return status.Errorf(grpccodes.NotFound, "participant %d is not in the chat", in.ClientId)
+	}
+	return nil
+}
+
+// dedupWindow is the number of message ids the server remembers per publisher
+const dedupWindow = 128
+
+// delivery is the outcome of broadcasting a published message, which the
+// retries of the publish are answered with
+type delivery struct {
+	done       chan struct{} // closed once delivered and recipients are set
+	delivered  int64
+	recipients int64
+}
+
+// delivery returns the delivery of the message the publisher has sent with the
+// same id within the last dedupWindow messages and true, or a new delivery for
+// the message and false. Messages without an id are never duplicates.
+func (s *Server) delivery(in *proto.ClientInfo) (*delivery, bool) {
+	result := &delivery{done: make(chan struct{})}
+	if in.MessageId == "" {
+		return result, false
+	}
+
+	s.mu.Lock()
+	window, ok := s.published[in.ClientId]
+	if !ok {
+		window = dedup.NewWindow(dedupWindow)
+		s.published[in.ClientId] = window
+	}
+	s.mu.Unlock()
+	earlier, duplicate := window.LoadOrStore(in.MessageId, result)
+	return earlier.(*delivery), duplicate
+}
+
+// when participant sends a message to a single other participant
+func (s *Server) DirectMessage(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	lamportTime := s.received("dm", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d sends a direct message to Participant %d", in.ClientId, in.RecipientId))
+	slog.Info("participant sends direct message", logging.Event, "dm", logging.Participant, in.ClientId, "recipient", in.RecipientId, logging.Lamport, lamportTime)
+	if err := s.checkMessage(in); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	recipient := s.find(in.RecipientId)
+	if sender := s.find(in.ClientId); sender != nil {
+		in.Nickname = sender.Nickname
+	}
+	s.mu.Unlock()
+	if recipient == nil {
+		return nil, status.Errorf(grpccodes.NotFound, "participant %d is not in the chat", in.RecipientId)
+	}
+
+	if err := s.deliverMessage(ctx, in, recipient); err != nil {
+		return nil, status.Errorf(grpccodes.Unavailable, "could not deliver to participant %d: %v", in.RecipientId, err)
+	}
 	return &proto.ServerInfo{
-		ServerName: s.name,
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
 	}, nil
 }
 
+// deliverMessage sends a published or direct message to one participant in its own span
+func (s *Server) deliverMessage(ctx context.Context, in *proto.ClientInfo, participant *proto.ClientInfo) error {
+	ctx, span := tracing.Tracer().Start(ctx, "deliver message", trace.WithAttributes(
+		attribute.Int64("chitty.publisher", in.ClientId),
+		attribute.Int64("chitty.recipient", participant.ClientId),
+	))
+	defer span.End()
+
+	lamportTime, vectorClock := s.send("broadcast", participantHost(participant.ClientId),
+		fmt.Sprintf("%s broadcasts message %q from Participant %d", s.name, in.Message, in.ClientId))
+	span.SetAttributes(attribute.Int("chitty.lamport", lamportTime))
+	slog.Info("server broadcasts message", logging.Event, "broadcast", logging.Participant, in.ClientId, "recipient", participant.ClientId, logging.Lamport, lamportTime)
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeouts.Delivery)
+	defer cancel()
+
+	start := time.Now()
+	participantClient, err := s.connectToClient(participant)
+	if err != nil {
+		return err
+	}
+	_, err = participantClient.ClientMessageReturn(ctx, &proto.ClientInfo{
+		ClientId:     in.ClientId,
+		LamportTime:  int64(lamportTime),
+		Message:      in.Message,
+		Announcement: in.Announcement,
+		RecipientId:  in.RecipientId,
+		Nickname:     in.Nickname,
+		Room:         in.Room,
+		MessageId:    in.MessageId,
+		VectorClock:  vectorClock,
+	})
+	observeDelivery(participant.ClientId, start, err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delivery failed")
+		slog.Warn("could not deliver message", logging.Event, "broadcast", logging.Participant, in.ClientId, "recipient", participant.ClientId, "error", err)
+	}
+	return err
+}
+
 // when participant joins server
 func (s *Server) ParticipantJoins(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
 	// updates lamport time depending on participant
-	if s.lamportTime < int(in.LamportTime) {
-		s.lamportTime = int(in.LamportTime)
-	}
-	s.lamportTime++
-	log.Printf("Participant %d joins %s at Lamport time %d\n", in.ClientId, s.name, s.lamportTime)
+	lamportTime := s.received("join", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d joins %s", in.ClientId, s.name))
+	slog.Info("participant joins", logging.Event, "join", logging.Participant, in.ClientId, logging.Lamport, lamportTime)
 
-	// Assuming that all clientIds are unique
-	s.participants = append(s.participants, int(in.PortNumber))
+	if in.Room == "" {
+		in.Room = lobby
+	} else {
+		room, err := checkRoom(in.Room)
+		if err != nil {
+			return nil, err
+		}
+		in.Room = room
+	}
+	s.mu.Lock()
+	if s.find(in.ClientId) != nil {
+		s.mu.Unlock()
+		return nil, status.Errorf(grpccodes.AlreadyExists, "participant %d is already in the chat", in.ClientId)
+	}
+	s.participants = append(s.participants, in)
+	participants := append([]*proto.ClientInfo(nil), s.participants...)
+	s.mu.Unlock()
+	joins.Inc()
+	s.webhooks.Send(webhook.Event{
+		Type:        webhook.Join,
+		ClientID:    in.ClientId,
+		Nickname:    in.Nickname,
+		Room:        in.Room,
+		LamportTime: int64(lamportTime),
+	})
 
-	// need to be broadcast to all existing participants
-	for _, port := range s.participants {
+	// need to be broadcast to all existing participants that serve a
+	// ParticipantService; a REST caller may join without one
+	for _, participant := range participants {
+		if participant.Address == "" && participant.PortNumber == 0 {
+			continue
+		}
 
-		clientConn, _ := connectToClient(port)
+		clientConn, err := s.connectToClient(participant)
+		if err != nil {
+			slog.Warn("could not deliver join broadcast", logging.Event, "join-broadcast", logging.Participant, in.ClientId, "recipient", participant.ClientId, "error", err)
+			continue
+		}
 
-		s.lamportTime++
-		log.Printf("%s broadcasts join message to Participant %d at Lamport time %d", s.name, in.ClientId, s.lamportTime)
+		var vectorClock map[string]int64
+		lamportTime, vectorClock = s.send("join-broadcast", participantHost(participant.ClientId), fmt.Sprintf("%s broadcasts that Participant %d joined", s.name, in.ClientId))
+		slog.Info("server broadcasts join", logging.Event, "join-broadcast", logging.Participant, in.ClientId, "recipient", participant.ClientId, logging.Lamport, lamportTime)
 
 		// send join message to participant
-		_, err := clientConn.ClientJoinReturn(context.Background(), &proto.ClientInfo{
+		deliveryCtx, cancel := context.WithTimeout(context.Background(), s.opts.Timeouts.Delivery)
+		start := time.Now()
+		_, err = clientConn.ClientJoinReturn(deliveryCtx, &proto.ClientInfo{
 			ClientId:    in.ClientId,
-			LamportTime: int64(s.lamportTime),
+			LamportTime: int64(lamportTime),
+			VectorClock: vectorClock,
 		})
+		cancel()
+		observeDelivery(participant.ClientId, start, err)
 		if err != nil {
-			log.Printf("%v", err)
+			slog.Warn("could not deliver join broadcast", logging.Event, "join-broadcast", logging.Participant, in.ClientId, "recipient", participant.ClientId, "error", err)
 		}
 
 	}
 
 	return &proto.ServerInfo{
-		LamportTime: int64(s.lamportTime),
+		LamportTime: int64(lamportTime),
 	}, nil
 }
 
-func connectToClient(port int) (proto.ParticipantServiceClient, error) {
-	// Dial the server at the specified port.
-	conn, err := grpc.Dial("localhost:"+strconv.Itoa(port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+// when participant leaves server
+func (s *Server) ParticipantLeaves(ctx context.Context, in *proto.ClientInfo) (*proto.ServerInfo, error) {
+	s.received("leave", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d leaves %s", in.ClientId, s.name))
+
+	// the remaining participants are told that it left
+	if !s.removeParticipant(ctx, in.ClientId, false) {
+		return nil, status.Errorf(grpccodes.NotFound, "participant %d is not in the chat", in.ClientId)
+	}
+	return &proto.ServerInfo{
+		ServerName:  s.name,
+		LamportTime: int64(s.clock()),
+	}, nil
+}
+
+// when participant asks who is in the chat (used for the floor requests)
+func (s *Server) Participants(ctx context.Context, in *proto.ClientInfo) (*proto.ParticipantList, error) {
+	lamportTime := s.received("participants", participantHost(in.ClientId), in.LamportTime, in.VectorClock, fmt.Sprintf("Participant %d asks for the participant list", in.ClientId))
+	slog.Debug("participant asks for the participant list", logging.Event, "participants", logging.Participant, in.ClientId, logging.Lamport, lamportTime)
+
+	participants := s.participantList()
+
+	lamportTime, vectorClock := s.send("participants-reply", participantHost(in.ClientId), fmt.Sprintf("%s sends %d participants", s.name, len(participants)))
+	return &proto.ParticipantList{
+		Participants: participants,
+		LamportTime:  int64(lamportTime),
+		VectorClock:  vectorClock,
+	}, nil
+}
+
+// participantList returns the id and address of every participant in the chat
+func (s *Server) participantList() []*proto.ClientInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	participants := make([]*proto.ClientInfo, 0, len(s.participants))
+	for _, participant := range s.participants {
+		participants = append(participants, &proto.ClientInfo{
+			ClientId:   participant.ClientId,
+			PortNumber: participant.PortNumber,
+			Address:    participant.Address,
+			Nickname:   participant.Nickname,
+			Room:       participant.Room,
+		})
+	}
+	return participants
+}
+
+// removeParticipant takes a participant out of the chat and broadcasts that it
+// left to the remaining participants, and to the participant itself if it was
+// kicked. It reports whether the participant was in the chat.
+func (s *Server) removeParticipant(ctx context.Context, clientID int64, kicked bool) bool {
+	s.mu.Lock()
+	var removed *proto.ClientInfo
+	for i, participant := range s.participants {
+		if participant.ClientId == clientID {
+			removed = participant
+			s.participants = append(s.participants[:i:i], s.participants[i+1:]...)
+			break
+		}
+	}
+	if s.floorHolder == clientID {
+		s.floorHolder = 0 // a participant that is gone cannot release it
+	}
+	participants := append([]*proto.ClientInfo(nil), s.participants...)
+	s.mu.Unlock()
+
+	if removed == nil {
+		return false
+	}
+	leaves.Inc()
+
+	reason := "left"
+	if kicked {
+		reason = "kicked"
+		participants = append(participants, removed)
+	}
+	slog.Info("participant leaves", logging.Event, "leave", logging.Participant, clientID, "reason", reason, logging.Lamport, s.clock())
+	s.webhooks.Send(webhook.Event{
+		Type:        webhook.Leave,
+		ClientID:    clientID,
+		Nickname:    removed.Nickname,
+		Room:        removed.Room,
+		Message:     reason,
+		LamportTime: int64(s.clock()),
+	})
+
+	for _, participant := range participants {
+		clientConn, err := s.connectToClient(participant)
+		if err != nil {
+			slog.Warn("could not deliver leave broadcast", logging.Event, "leave-broadcast", logging.Participant, clientID, "recipient", participant.ClientId, "error", err)
+			continue
+		}
+
+		lamportTime, vectorClock := s.send("leave-broadcast", participantHost(participant.ClientId), fmt.Sprintf("%s broadcasts that Participant %d %s", s.name, clientID, reason))
+		slog.Info("server broadcasts leave", logging.Event, "leave-broadcast", logging.Participant, clientID, "recipient", participant.ClientId, logging.Lamport, lamportTime)
+
+		deliveryCtx, cancel := context.WithTimeout(ctx, s.opts.Timeouts.Delivery)
+		start := time.Now()
+		_, err = clientConn.ClientLeaveReturn(deliveryCtx, &proto.ClientInfo{
+			ClientId:    clientID,
+			LamportTime: int64(lamportTime),
+			Message:     reason,
+			VectorClock: vectorClock,
+		})
+		cancel()
+		observeDelivery(participant.ClientId, start, err)
+		if err != nil {
+			slog.Warn("could not deliver leave broadcast", logging.Event, "leave-broadcast", logging.Participant, clientID, "recipient", participant.ClientId, "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	if conn, ok := s.clients[clientID]; ok {
+		conn.Close()
+		delete(s.clients, clientID)
+	}
+	delete(s.published, clientID)
+	s.mu.Unlock()
+	return true
+}
+
+// send advances the Lamport clock for sending kind to peer and logs the send in
+// the same step, so that concurrent calls log the server's events in Lamport
+// order. It returns the new time and the vector clock to attach to the message.
+func (s *Server) send(kind, peer, text string) (int, map[string]int64) {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	s.lamportTime++
+	vectorClock := s.events.Send(kind, peer, s.lamportTime, text)
+	if s.opts.Clock == config.LamportOnly {
+		vectorClock = nil
+	}
+	return s.lamportTime, vectorClock
+}
+
+// received merges the timestamps of a message of kind from peer into the clocks
+// and logs the receive in the same step. It returns the new Lamport time.
+func (s *Server) received(kind, peer string, lamportTime int64, vectorClock map[string]int64, text string) int {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	if s.lamportTime < int(lamportTime) {
+		s.lamportTime = int(lamportTime)
+	}
+	s.lamportTime++
+	s.events.Receive(kind, peer, s.lamportTime, vectorClock, text)
+	return s.lamportTime
+}
+
+// local advances the Lamport clock for a local event, logs it and returns the new time
+func (s *Server) local(kind, text string) int {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	s.lamportTime++
+	s.events.LocalEvent(kind, s.lamportTime, text)
+	return s.lamportTime
+}
+
+// clock returns the current Lamport time
+func (s *Server) clock() int {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	return s.lamportTime
+}
+
+// participantHost is the name of a participant in the event log
+func participantHost(clientID int64) string {
+	return fmt.Sprintf("client-%d", clientID)
+}
+
+// connectToClient returns a (cached) connection to a participant's ParticipantService
+func (s *Server) connectToClient(participant *proto.ClientInfo) (proto.ParticipantServiceClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conn, ok := s.clients[participant.ClientId]; ok {
+		return proto.NewParticipantServiceClient(conn), nil
+	}
+	// Dial the client at its advertised address.
+	address := participantAddress(participant)
+	options := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials()), tracing.DialOption()}, s.opts.DialOptions...)
+	conn, err := grpc.Dial(address, options...)
 	if err != nil {
-		log.Fatalf("Could not connect to port %d", port)
+		return nil, fmt.Errorf("could not connect to %s: %w", address, err)
 	}
+	s.clients[participant.ClientId] = conn
 	return proto.NewParticipantServiceClient(conn), nil
 }
+
+// participantAddress returns the host:port of the participant's ParticipantService
+func participantAddress(participant *proto.ClientInfo) string {
+	if participant.Address != "" {
+		return participant.Address
+	}
+	return "localhost:" + strconv.Itoa(int(participant.PortNumber))
+}
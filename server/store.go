@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// historyFile holds the recorded messages in the data directory, one JSON
+// object per line, in the order they were published in each room
+const historyFile = "history.jsonl"
+
+// store appends the recorded messages to the history file, from which a
+// restarted server gets the history of every room back. A nil store keeps
+// nothing.
+type store struct {
+	file *os.File
+}
+
+// openStore loads the history kept in dir, which is created if need be, and
+// opens the history file for appending. The file is rewritten with the last
+// historySize messages of every room first, so it does not grow without end.
+// It also returns the highest Lamport time of a kept message.
+func openStore(dir string) (*store, map[string][]*proto.ClientInfo, int, error) {
+	history := make(map[string][]*proto.ClientInfo)
+	if dir == "" {
+		return nil, history, 0, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, 0, err
+	}
+	path := filepath.Join(dir, historyFile)
+
+	lamportTime, err := readHistory(path, history)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if err := writeHistory(path, history); err != nil {
+		return nil, nil, 0, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return &store{file: file}, history, lamportTime, nil
+}
+
+// readHistory adds the messages in the file at path to history, if it exists
+func readHistory(path string, history map[string][]*proto.ClientInfo) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lamportTime := 0
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		message := new(proto.ClientInfo)
+		if err := protojson.Unmarshal(scanner.Bytes(), message); err != nil {
+			return 0, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		messages := append(history[message.Room], message)
+		if len(messages) > historySize {
+			messages = messages[len(messages)-historySize:]
+		}
+		history[message.Room] = messages
+		lamportTime = max(lamportTime, int(message.LamportTime))
+	}
+	return lamportTime, scanner.Err()
+}
+
+// writeHistory replaces the file at path with history
+func writeHistory(path string, history map[string][]*proto.ClientInfo) error {
+	rooms := make([]string, 0, len(history))
+	for room := range history {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+
+	temporary := path + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, room := range rooms {
+		for _, message := range history[room] {
+			if err := writeMessage(w, message); err != nil {
+				file.Close()
+				return err
+			}
+		}
+	}
+	if err := errors.Join(w.Flush(), file.Close()); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+func writeMessage(w io.Writer, message *proto.ClientInfo) error {
+	line, err := protojson.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// append adds a recorded message to the history file
+func (st *store) append(message *proto.ClientInfo) error {
+	if st == nil {
+		return nil
+	}
+	return writeMessage(st.file, message)
+}
+
+// close closes the history file, once
+func (st *store) close() error {
+	if st == nil || st.file == nil {
+		return nil
+	}
+	err := st.file.Close()
+	st.file = nil
+	return err
+}
@@ -0,0 +1,290 @@
+// Package webhook forwards chat events to HTTP endpoints registered in the
+// server config, so that other tools can follow the chat.
+//
+// Every event is POSTed as JSON to the hooks whose filter selects it:
+//
+//	{"type":"message","clientId":2,"nickname":"bob","room":"lobby","message":"hi","lamportTime":7,"time":"..."}
+//
+// When a hook has a secret, the body is signed with HMAC-SHA256 and the
+// signature sent as "X-Chitty-Signature: sha256=<hex>", which the receiver
+// recomputes with Verify. A failed delivery (network error, 429 or 5xx) is
+// retried with exponential backoff; an event that still cannot be delivered,
+// or is rejected with another status, is appended to the dead-letter file, as
+// are the events still waiting when Close gives up.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Tien197/Chitty-Chat/config"
+	"github.com/Tien197/Chitty-Chat/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event types, also the names used in the filters of the config.
+const (
+	Join    = "join"
+	Leave   = "leave"
+	Message = "message"
+)
+
+// Headers of every delivery.
+const (
+	SignatureHeader = "X-Chitty-Signature"
+	EventHeader     = "X-Chitty-Event"
+)
+
+// queueSize is the number of events a hook can fall behind before new ones go to the dead-letter file
+const queueSize = 256
+
+// errClosed is the error dead-lettered with the events Close gave up on
+var errClosed = errors.New("the dispatcher was closed before the event was delivered")
+
+// Event is the body of a delivery.
+type Event struct {
+	Type        string    `json:"type"`
+	ClientID    int64     `json:"clientId"`
+	Nickname    string    `json:"nickname,omitempty"`
+	Room        string    `json:"room,omitempty"`
+	Message     string    `json:"message,omitempty"` // the message, or "left" or "kicked" for a leave
+	LamportTime int64     `json:"lamportTime"`       // the server's Lamport time of the event
+	Time        time.Time `json:"time"`
+}
+
+// Dispatcher delivers events to the hooks, each from its own queue so that a
+// slow or failing hook does not hold back the chat or the other hooks.
+type Dispatcher struct {
+	Client  *http.Client  // used for every delivery
+	Backoff time.Duration // wait before the first retry, doubled for each further one
+
+	hooks   []*hook
+	retries int
+	wg      sync.WaitGroup
+	ctx     context.Context // cancelled when Close gives up on the events still waiting
+	cancel  context.CancelFunc
+
+	closeMu sync.RWMutex // held for reading while queueing, so Close does not close a queue under Send
+	closed  bool
+
+	mu         sync.Mutex // guards deadLetter
+	deadLetter io.Writer  // nil when failed events are only logged
+}
+
+type hook struct {
+	config.Webhook
+	queue chan Event
+}
+
+// deadLetter is one line of the dead-letter file.
+type deadLetter struct {
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// New returns a dispatcher delivering to hooks and trying every event retries
+// times more before writing it to deadLetter, which may be nil. The hooks are
+// served until Close.
+func New(hooks []config.Webhook, retries int, deadLetter io.Writer) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		Backoff:    500 * time.Millisecond,
+		retries:    retries,
+		ctx:        ctx,
+		cancel:     cancel,
+		deadLetter: deadLetter,
+	}
+	for _, h := range hooks {
+		h := &hook{Webhook: h, queue: make(chan Event, queueSize)}
+		d.hooks = append(d.hooks, h)
+		d.wg.Add(1)
+		go d.serve(h)
+	}
+	return d
+}
+
+// Open returns a dispatcher that appends failed events to the file at path, or
+// only logs them when path is empty.
+func Open(hooks []config.Webhook, retries int, path string) (*Dispatcher, error) {
+	if path == "" {
+		return New(hooks, retries, nil), nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return New(hooks, retries, file), nil
+}
+
+// Send queues event for every hook whose filter selects it. It does not wait
+// for the deliveries, and drops the event once the dispatcher is closed.
+func (d *Dispatcher) Send(event Event) {
+	d.closeMu.RLock()
+	defer d.closeMu.RUnlock()
+	if d.closed {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, h := range d.hooks {
+		if len(h.Events) > 0 && !slices.Contains(h.Events, event.Type) {
+			continue
+		}
+		select {
+		case h.queue <- event:
+		default:
+			d.fail(h, event, 0, fmt.Errorf("more than %d events waiting", queueSize))
+		}
+	}
+}
+
+// Close delivers the queued events and stops the dispatcher. Once ctx is done
+// it stops retrying and writes the events not delivered yet to the dead-letter
+// file instead, so that a dead endpoint does not hold up a shutdown. Closing
+// it again does nothing.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.closeMu.Lock()
+	if d.closed {
+		d.closeMu.Unlock()
+		return nil
+	}
+	d.closed = true
+	for _, h := range d.hooks {
+		close(h.queue)
+	}
+	d.closeMu.Unlock()
+
+	served := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-ctx.Done():
+		d.cancel()
+		<-served
+	}
+	d.cancel()
+
+	if closer, ok := d.deadLetter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// serve delivers the events of one hook in order
+func (d *Dispatcher) serve(h *hook) {
+	defer d.wg.Done()
+	for event := range h.queue {
+		if d.ctx.Err() != nil {
+			d.fail(h, event, 0, errClosed)
+			continue
+		}
+		d.deliver(h, event)
+	}
+}
+
+// deliver posts event to h, retrying with backoff, and dead-letters it when that fails
+func (d *Dispatcher) deliver(h *hook, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.fail(h, event, 0, err)
+		return
+	}
+
+	wait := d.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(h, event.Type, body)
+		if err == nil {
+			slog.Debug("webhook delivered", logging.Event, "webhook", "url", h.URL, "type", event.Type, logging.Lamport, event.LamportTime, "attempt", attempt)
+			return
+		}
+		if !retry || attempt > d.retries {
+			d.fail(h, event, attempt, err)
+			return
+		}
+		slog.Info("webhook delivery failed, retrying", logging.Event, "webhook", "url", h.URL, "type", event.Type, "attempt", attempt, "backoff", wait, "error", err)
+		select {
+		case <-time.After(wait):
+		case <-d.ctx.Done():
+			d.fail(h, event, attempt, errClosed)
+			return
+		}
+		wait *= 2
+	}
+}
+
+// post makes one delivery and reports whether a failure is worth retrying
+func (d *Dispatcher) post(h *hook, eventType string, body []byte) (retry bool, err error) {
+	request, err := http.NewRequestWithContext(d.ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, eventType)
+	if h.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		if d.ctx.Err() != nil {
+			return false, errClosed
+		}
+		return true, err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return false, nil
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return true, fmt.Errorf("%s", response.Status)
+	}
+	return false, fmt.Errorf("%s", response.Status)
+}
+
+// fail logs an event that could not be delivered and writes it to the dead-letter file
+func (d *Dispatcher) fail(h *hook, event Event, attempts int, err error) {
+	slog.Warn("webhook delivery failed", logging.Event, "webhook", "url", h.URL, "type", event.Type, logging.Lamport, event.LamportTime, "attempts", attempts, "error", err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.deadLetter == nil {
+		return
+	}
+	line, _ := json.Marshal(deadLetter{URL: h.URL, Event: event, Error: err.Error(), Attempts: attempts, Time: time.Now()})
+	if _, err := d.deadLetter.Write(append(line, '\n')); err != nil {
+		slog.Error("could not write to the dead-letter file", "error", err)
+	}
+}
+
+// Sign returns the signature header value of body for secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body for secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, body)))
+}
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Tien197/Chitty-Chat/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver records the requests made to it and answers them with the next
+// status of statuses, the last one once they run out
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+
+		r.mu.Lock()
+		r.bodies = append(r.bodies, body)
+		r.headers = append(r.headers, request.Header)
+		status := r.statuses[0]
+		if len(r.statuses) > 1 {
+			r.statuses = r.statuses[1:]
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) requests() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+// types returns the event types the receiver was sent, in order
+func (r *receiver) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var types []string
+	for _, header := range r.headers {
+		types = append(types, header.Get(EventHeader))
+	}
+	return types
+}
+
+// newDispatcher returns a dispatcher with a short backoff and the buffer its dead letters are written to
+func newDispatcher(hooks []config.Webhook, retries int) (*Dispatcher, *bytes.Buffer) {
+	var deadLetters bytes.Buffer
+	d := New(hooks, retries, &deadLetters)
+	d.Backoff = time.Millisecond
+	return d, &deadLetters
+}
+
+func closeDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("could not close the dispatcher: %v", err)
+	}
+}
+
+func readDeadLetters(t *testing.T, buffer *bytes.Buffer) []deadLetter {
+	t.Helper()
+
+	var letters []deadLetter
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var letter deadLetter
+		if err := json.Unmarshal([]byte(line), &letter); err != nil {
+			t.Fatalf("could not parse the dead letter %q: %v", line, err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters
+}
+
+func TestSignatureVerifies(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	d, _ := newDispatcher([]config.Webhook{{URL: r.URL, Secret: "s3cret"}}, 0)
+
+	d.Send(Event{Type: Message, ClientID: 2, Message: "hi", LamportTime: 7})
+	closeDispatcher(t, d)
+
+	if r.requests() != 1 {
+		t.Fatalf("the receiver got %d requests, want 1", r.requests())
+	}
+	body, signature := r.bodies[0], r.headers[0].Get(SignatureHeader)
+	if !Verify("s3cret", body, signature) {
+		t.Errorf("the signature %q does not verify the body %s", signature, body)
+	}
+	if Verify("other", body, signature) {
+		t.Error("the signature verifies with another secret")
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.Type != Message || event.Message != "hi" || event.LamportTime != 7 {
+		t.Errorf("the receiver got %s, want the message event", body)
+	}
+}
+
+func TestRetriesServerErrorsAndTooManyRequests(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	d, deadLetters := newDispatcher([]config.Webhook{{URL: r.URL}}, 3)
+
+	d.Send(Event{Type: Join, ClientID: 1})
+	closeDispatcher(t, d)
+
+	if r.requests() != 3 {
+		t.Errorf("the receiver got %d requests, want the event retried twice", r.requests())
+	}
+	if letters := readDeadLetters(t, deadLetters); len(letters) != 0 {
+		t.Errorf("the delivered event was dead-lettered: %v", letters)
+	}
+}
+
+func TestOtherClientErrorsAreNotRetried(t *testing.T) {
+	r := newReceiver(t, http.StatusBadRequest)
+	d, deadLetters := newDispatcher([]config.Webhook{{URL: r.URL}}, 3)
+
+	d.Send(Event{Type: Join, ClientID: 1})
+	closeDispatcher(t, d)
+
+	if r.requests() != 1 {
+		t.Errorf("the receiver got %d requests, want 1", r.requests())
+	}
+	if letters := readDeadLetters(t, deadLetters); len(letters) != 1 || letters[0].Attempts != 1 {
+		t.Errorf("dead letters %v, want the event after one attempt", letters)
+	}
+}
+
+func TestFailedEventIsDeadLettered(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	d, deadLetters := newDispatcher([]config.Webhook{{URL: r.URL}}, 2)
+
+	d.Send(Event{Type: Leave, ClientID: 3, Message: "left", LamportTime: 9})
+	closeDispatcher(t, d)
+
+	letters := readDeadLetters(t, deadLetters)
+	if len(letters) != 1 {
+		t.Fatalf("dead letters %v, want one", letters)
+	}
+	letter := letters[0]
+	if letter.URL != r.URL || letter.Event.Type != Leave || letter.Event.ClientID != 3 || letter.Attempts != 3 || letter.Error != "500 Internal Server Error" {
+		t.Errorf("dead letter %+v, want the leave of participant 3 after 3 attempts with the status", letter)
+	}
+}
+
+func TestFiltersSelectTheEvents(t *testing.T) {
+	joins, messages, everything := newReceiver(t, http.StatusOK), newReceiver(t, http.StatusOK), newReceiver(t, http.StatusOK)
+	d, _ := newDispatcher([]config.Webhook{
+		{URL: joins.URL, Events: []string{Join}},
+		{URL: messages.URL, Events: []string{Message, Leave}},
+		{URL: everything.URL},
+	}, 0)
+
+	for _, eventType := range []string{Join, Message, Leave} {
+		d.Send(Event{Type: eventType})
+	}
+	closeDispatcher(t, d)
+
+	for _, test := range []struct {
+		name string
+		r    *receiver
+		want string
+	}{
+		{"join", joins, "join"},
+		{"message and leave", messages, "message leave"},
+		{"unfiltered", everything, "join message leave"},
+	} {
+		if got := strings.Join(test.r.types(), " "); got != test.want {
+			t.Errorf("the %s hook got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestQueueOverflowIsDeadLettered(t *testing.T) {
+	release := make(chan struct{})
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer blocked.Close()
+	d, deadLetters := newDispatcher([]config.Webhook{{URL: blocked.URL}}, 0)
+
+	// one event may be in flight, queueSize wait and the rest overflow
+	for i := 0; i < queueSize+2; i++ {
+		d.Send(Event{Type: Message, LamportTime: int64(i)})
+	}
+	overflow := readDeadLetters(t, deadLetters)
+	close(release)
+	if len(overflow) == 0 {
+		t.Fatal("no event overflowed the queue")
+	}
+	for _, letter := range overflow {
+		if !strings.Contains(letter.Error, "events waiting") || letter.Attempts != 0 {
+			t.Errorf("dead letter %+v, want an overflow without attempts", letter)
+		}
+	}
+	closeDispatcher(t, d)
+}
+
+func TestCloseGivesUpOnADeadEndpoint(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	d, deadLetters := newDispatcher([]config.Webhook{{URL: r.URL}}, 5)
+	d.Backoff = time.Hour
+
+	for i := 0; i < 3; i++ {
+		d.Send(Event{Type: Message, LamportTime: int64(i)})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("could not close the dispatcher: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("closing took %v", elapsed)
+	}
+
+	letters := readDeadLetters(t, deadLetters)
+	if len(letters) != 3 {
+		t.Fatalf("dead letters %v, want every event", letters)
+	}
+	for _, letter := range letters {
+		if letter.Error != errClosed.Error() {
+			t.Errorf("dead letter %+v, want the events given up on", letter)
+		}
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	d, _ := newDispatcher([]config.Webhook{{URL: newReceiver(t, http.StatusOK).URL}}, 0)
+	closeDispatcher(t, d)
+	closeDispatcher(t, d)
+}